@@ -0,0 +1,126 @@
+// Package alert notifies external systems when upstream failures pile up.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// Alert is the JSON body posted to the webhook.
+type Alert struct {
+	Category  string    `json:"category"`
+	Count     int       `json:"count"`
+	Window    string    `json:"window"`
+	LastError string    `json:"last_error"`
+	Time      time.Time `json:"time"`
+}
+
+// Webhook posts an Alert when at least threshold upstream errors of one category happen within
+// window. After an alert, the category stays quiet for cooldown so a sustained outage produces one
+// notification instead of a flood.
+type Webhook struct {
+	url       string
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	client    *http.Client
+	now       func() time.Time
+
+	mu       sync.Mutex
+	events   map[string][]time.Time
+	lastSent map[string]time.Time
+}
+
+// NewWebhook creates a notifier posting to url.
+func NewWebhook(url string, threshold int, window, cooldown time.Duration) *Webhook {
+	return &Webhook{
+		url:       url,
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		now:       time.Now,
+		events:    make(map[string][]time.Time),
+		lastSent:  make(map[string]time.Time),
+	}
+}
+
+// Observe records err and sends an alert in the background when its category crosses the
+// threshold. Errors without a category are ignored. It is meant for geocode.WithErrorObserver.
+func (w *Webhook) Observe(err error) {
+	category := geocode.ErrorCategory(err)
+	if category == "" {
+		return
+	}
+
+	alert, ok := w.record(category, err)
+	if ok {
+		go w.send(alert)
+	}
+}
+
+// record adds an event and reports whether an alert is due, resetting the category when it is.
+func (w *Webhook) record(category string, err error) (Alert, bool) {
+	now := w.now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	events := w.events[category]
+	cutoff := now.Add(-w.window)
+	kept := events[:0]
+	for _, at := range events {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	kept = append(kept, now)
+	w.events[category] = kept
+
+	if len(kept) < w.threshold {
+		return Alert{}, false
+	}
+	if last, ok := w.lastSent[category]; ok && now.Sub(last) < w.cooldown {
+		return Alert{}, false
+	}
+	w.lastSent[category] = now
+	w.events[category] = nil
+	return Alert{
+		Category:  category,
+		Count:     len(kept),
+		Window:    w.window.String(),
+		LastError: err.Error(),
+		Time:      now,
+	}, true
+}
+
+func (w *Webhook) send(alert Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("failed to encode alert: %v", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("failed to build alert request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		log.Printf("failed to send %s alert: %v", alert.Category, err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		log.Printf("alert webhook returned status %d for %s alert", resp.StatusCode, alert.Category)
+	}
+}
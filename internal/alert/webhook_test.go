@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+var (
+	errQuota  = &geocode.UpstreamStatusError{API: "google maps api", StatusCode: http.StatusTooManyRequests}
+	errDenied = &geocode.APIStatusError{API: "google maps api", Status: "REQUEST_DENIED"}
+	errServer = &geocode.UpstreamStatusError{API: "google maps api", StatusCode: http.StatusBadGateway}
+)
+
+func TestWebhookRecord(t *testing.T) {
+	type event struct {
+		after time.Duration
+		err   error
+	}
+	tests := []struct {
+		name   string
+		events []event
+		want   []string
+	}{
+		{
+			name:   "below the threshold",
+			events: []event{{0, errQuota}, {time.Second, errQuota}},
+		},
+		{
+			name:   "crossing the threshold",
+			events: []event{{0, errQuota}, {time.Second, errQuota}, {time.Second, errQuota}},
+			want:   []string{geocode.CategoryQuota},
+		},
+		{
+			name:   "debounced within the cooldown",
+			events: []event{{0, errQuota}, {0, errQuota}, {0, errQuota}, {time.Second, errQuota}, {0, errQuota}, {0, errQuota}},
+			want:   []string{geocode.CategoryQuota},
+		},
+		{
+			name:   "alerts again after the cooldown",
+			events: []event{{0, errQuota}, {0, errQuota}, {0, errQuota}, {10 * time.Minute, errQuota}, {0, errQuota}, {0, errQuota}},
+			want:   []string{geocode.CategoryQuota, geocode.CategoryQuota},
+		},
+		{
+			name:   "events outside the window expire",
+			events: []event{{0, errQuota}, {0, errQuota}, {2 * time.Minute, errQuota}},
+		},
+		{
+			name:   "categories count separately",
+			events: []event{{0, errQuota}, {0, errDenied}, {0, errServer}, {0, errDenied}, {0, errDenied}},
+			want:   []string{geocode.CategoryDenied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			w := NewWebhook("http://alerts.invalid", 3, time.Minute, 5*time.Minute)
+			w.now = func() time.Time { return now }
+
+			var got []string
+			for _, e := range tt.events {
+				now = now.Add(e.after)
+				if alert, ok := w.record(geocode.ErrorCategory(e.err), e.err); ok {
+					got = append(got, alert.Category)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("alerts = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("alerts = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookObservePostsOneAlert(t *testing.T) {
+	alerts := make(chan Alert, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("decode alert: %v", err)
+		}
+		alerts <- alert
+	}))
+	defer hook.Close()
+
+	w := NewWebhook(hook.URL, 3, time.Minute, 5*time.Minute)
+	w.Observe(errors.New("not an upstream failure"))
+	for i := 0; i < 10; i++ {
+		w.Observe(errDenied)
+	}
+
+	select {
+	case alert := <-alerts:
+		if alert.Category != geocode.CategoryDenied || alert.Count != 3 || alert.Window != "1m0s" {
+			t.Errorf("alert = %+v, want denied with 3 errors in 1m0s", alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert posted")
+	}
+	select {
+	case alert := <-alerts:
+		t.Errorf("unexpected second alert %+v", alert)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -3,28 +3,282 @@ package config
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config contains application configuration sourced from environment variables.
 type Config struct {
+	// GoogleAPIKey is read from the file named by GOOGLE_MAPS_API_KEY_FILE when set, otherwise from
+	// GOOGLE_MAPS_API_KEY.
 	GoogleAPIKey string
 	ServerPort   string
+
+	// GoogleClientID and GoogleSigningSecret enable the premium plan URL signing flow instead of
+	// GoogleAPIKey. GoogleChannel is sent with every request for usage reporting.
+	GoogleClientID      string
+	GoogleSigningSecret string
+	GoogleChannel       string
+
+	// TLSCertFile and TLSKeyFile enable HTTPS (and HTTP/2) when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// InboundSigningSecret, when set, requires every request to carry a valid HMAC signature made
+	// within InboundSigningWindow of the server clock.
+	InboundSigningSecret string
+	InboundSigningWindow time.Duration
+
+	// EnablePprof serves net/http/pprof on PprofAddr, a separate listener that defaults to loopback.
+	EnablePprof bool
+	PprofAddr   string
+
+	// CacheSnapshotEnabled persists the cache to CacheSnapshotPath on shutdown and restores it on startup.
+	CacheSnapshotEnabled bool
+	CacheSnapshotPath    string
+
+	// MetricsResetToken enables POST /metrics/reset, which zeroes the cache counters, for callers
+	// presenting it as a bearer token. The endpoint is disabled when it is empty.
+	MetricsResetToken string
+
+	// CacheGraceToken enables POST /cache/grace, which temporarily stretches cache TTLs, for callers
+	// presenting it as a bearer token. The endpoint is disabled when it is empty.
+	CacheGraceToken string
+
+	// CacheShards is the number of independently locked shards of the in-memory cache. It must be a
+	// power of two.
+	CacheShards int
+
+	// CanonicalCacheSize is how many raw addresses have their normalized form remembered, so
+	// recurring inputs skip preprocessing. Zero disables the canonicalization cache.
+	CanonicalCacheSize int
+
+	// CacheChurnLimit pauses caching of new entries for the rest of CacheChurnWindow once more new
+	// keys than this arrive within it. Zero disables the limit.
+	CacheChurnLimit  int
+	CacheChurnWindow time.Duration
+
+	// CacheStatsWindow is the sliding window used to compute the recent cache hit ratio.
+	CacheStatsWindow time.Duration
+
+	// MaxLookupDuration caps a single upstream lookup regardless of the caller's deadline.
+	MaxLookupDuration time.Duration
+
+	// OutboundIdleConnTimeout, OutboundMaxIdleConnsPerHost and OutboundKeepAlive tune the
+	// connections kept open to the Google Maps APIs.
+	OutboundIdleConnTimeout     time.Duration
+	OutboundMaxIdleConnsPerHost int
+	OutboundKeepAlive           time.Duration
+
+	// DefaultCountry lists names of the country appended to addresses that do not mention one. The
+	// first name is appended; all of them are recognized.
+	DefaultCountry []string
+
+	// ProviderTTLs overrides the cache TTL for results produced by a given provider, such as google
+	// or static. It takes precedence over PrecisionTTLs.
+	ProviderTTLs map[string]time.Duration
+
+	// PrecisionTTLs overrides the cache TTL for results of a given precision, such as ROOFTOP.
+	PrecisionTTLs map[string]time.Duration
+
+	// RouteTimeouts overrides the deadline of individual routes, keyed by path. Zero removes it.
+	RouteTimeouts map[string]time.Duration
+
+	// CollisionGuard only serves cached results looked up for an address compatible with the
+	// incoming one.
+	CollisionGuard bool
+
+	// DailyUpstreamCap limits Google requests per day; zero only counts them. Days start at
+	// DailyCapReset past midnight UTC.
+	DailyUpstreamCap int
+	DailyCapReset    time.Duration
+
+	// MaxInflightLookups caps concurrent upstream lookups; excess requests get 503. Zero disables it.
+	MaxInflightLookups int
+
+	// AdaptiveRateMin and AdaptiveRateMax bound the Google request rate, in requests per second,
+	// which halves on quota errors and climbs back on sustained success. A zero maximum disables it.
+	AdaptiveRateMin float64
+	AdaptiveRateMax float64
+
+	// ProviderTimeout bounds each provider attempt in the chain and MaxProviderAttempts caps how
+	// many providers are tried. Zero disables either limit.
+	ProviderTimeout     time.Duration
+	MaxProviderAttempts int
+
+	// TrustedProxies lists the CIDRs or addresses of proxies whose X-Forwarded-For header is trusted
+	// when resolving the client IP.
+	TrustedProxies []string
+
+	// FailFastDeniedThreshold shuts the server down, exiting with a non-zero status, after this many
+	// consecutive REQUEST_DENIED answers from Google. Zero disables it.
+	FailFastDeniedThreshold int
+
+	// AlertWebhookURL receives a JSON alert when AlertThreshold upstream errors of one category
+	// (quota, denied or upstream 5xx) happen within AlertWindow. Each category then stays quiet for
+	// AlertCooldown. Alerts are disabled when the URL is empty.
+	AlertWebhookURL string
+	AlertThreshold  int
+	AlertWindow     time.Duration
+	AlertCooldown   time.Duration
+
+	// StatsDAddr is the host:port metrics are sent to over UDP. Metrics are disabled when it is
+	// empty. StatsDPrefix is prepended to every metric name and StatsDTags enables DogStatsD tags.
+	StatsDAddr   string
+	StatsDPrefix string
+	StatsDTags   bool
+
+	// LogSampleRate is the fraction of requests logged, between 0 and 1. Server errors and requests
+	// slower than LogSlowThreshold are always logged.
+	LogSampleRate    float64
+	LogSlowThreshold time.Duration
+
+	// ReadinessTimeout bounds the dependency checks performed by /readyz.
+	ReadinessTimeout time.Duration
+
+	// FailureCacheTTL and NoResultsCacheTTL control how long failed lookups are remembered before
+	// the upstream is retried. Zero disables negative caching.
+	FailureCacheTTL   time.Duration
+	NoResultsCacheTTL time.Duration
+
+	// AddressBlocklist and AddressAllowlist hold filter rules: case-insensitive substrings, or
+	// regular expressions when prefixed with "re:".
+	AddressBlocklist []string
+	AddressAllowlist []string
+
+	// ResultTieBreak chooses among several results for one address: "first" keeps Google's first
+	// result, "address" picks the most precise one with the lowest formatted address.
+	ResultTieBreak string
+
+	// AddressFormat selects the address returned for Google results: "formatted" passes Google's
+	// formatted address through, "components" rebuilds it from AddressComponentOrder, or a default
+	// order when that is empty.
+	AddressFormat         string
+	AddressComponentOrder []string
+
+	// MinAddressLength is the shortest normalized address, in characters, sent to providers.
+	// Shorter addresses are rejected without a lookup. Zero disables the check.
+	MinAddressLength int
+
+	// CoordinateInputMode controls how "lat,lng" input to /geocode is handled: "allow", "reject" or
+	// "fallback".
+	CoordinateInputMode string
+
+	// CoordinateDecimals rounds output coordinates to this many decimal places. Zero disables rounding.
+	CoordinateDecimals int
+
+	// HideSource omits the source field from geocode responses.
+	HideSource bool
+	// HideSourceRoutes omits the source field only from the routes registered under these
+	// patterns.
+	HideSourceRoutes []string
+
+	// ValidateResponseStyle is how /validate reports its verdict: "body" answers 200 with a JSON
+	// body, "status" answers 204 for valid addresses and 422 for invalid ones.
+	ValidateResponseStyle string
+
+	// StrictQueryParams rejects /geocode requests carrying unknown query parameters, which usually
+	// point to a typo such as adress=, instead of ignoring them.
+	StrictQueryParams bool
+
+	// ResponseFreshness adds retrieved_at and expires_at to geocode responses.
+	ResponseFreshness bool
+
+	// ProblemJSON writes errors as RFC 7807 problem details.
+	ProblemJSON bool
+
+	// ResponseEnvelope wraps every response as {"data": ..., "error": ..., "meta": {...}}.
+	ResponseEnvelope bool
+
+	// DebugResponses lets clients request the raw upstream response with debug=true, truncated to
+	// DebugMaxBytes.
+	DebugResponses bool
+	DebugMaxBytes  int
+
+	// ExplainResponses lets clients request, with explain=true, how a /geocode result was produced:
+	// normalization, cache decision, provider attempts and the tie-break rule.
+	ExplainResponses bool
+
+	// DemoPage serves an HTML page at / for trying the service manually.
+	DemoPage bool
+
+	// ChaosFailureRate and ChaosLatency inject failures and delays into provider calls for testing
+	// fallback behavior. Both are off unless set explicitly and must never be set in production.
+	ChaosFailureRate float64
+	ChaosLatency     time.Duration
+
+	// StaticDatasetPath points to a JSON file of known address coordinates consulted before Google.
+	StaticDatasetPath string
 }
 
-// LoadEnvFile loads key=value pairs from the provided file into the process environment.
+// utf8BOM is the byte order mark some Windows editors write at the start of a file.
+const utf8BOM = "\ufeff"
+
+// LoadEnvFile loads key=value pairs from the provided file into the process environment. A leading
+// UTF-8 byte order mark and CRLF line endings are accepted. Values may reference variables defined
+// earlier in the file or in the environment as ${VAR}; undefined variables expand to an empty
+// string. Write $$ for a literal $.
 func LoadEnvFile(path string) error {
+	return loadEnvFile(path, false)
+}
+
+// LoadEnvFileStrict behaves like LoadEnvFile but fails when a value references an undefined variable.
+func LoadEnvFileStrict(path string) error {
+	return loadEnvFile(path, true)
+}
+
+func loadEnvFile(path string, strict bool) error {
+	return parseEnvFile(path, strict, func(key, value string) error {
+		return os.Setenv(key, value)
+	})
+}
+
+// ReadEnvFile parses the env file at path like LoadEnvFile, but returns its variables instead of
+// setting them in the process environment.
+func ReadEnvFile(path string) (map[string]string, error) {
+	vars := make(map[string]string)
+	err := parseEnvFile(path, false, func(key, value string) error {
+		vars[key] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// parseEnvFile passes each variable of the env file at path, in order, to set. References to other
+// variables are resolved against the environment, so set must make each variable visible to the
+// lookups of later lines; parseEnvFile tracks the ones it has set to that end.
+func parseEnvFile(path string, strict bool, set func(key, value string) error) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	defined := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if value, ok := defined[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+
 	scanner := bufio.NewScanner(file)
+	first := true
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		raw := scanner.Text()
+		if first {
+			raw = strings.TrimPrefix(raw, utf8BOM)
+			first = false
+		}
+		// TrimSpace also removes the carriage return left by CRLF line endings.
+		line := strings.TrimSpace(raw)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
@@ -37,7 +291,12 @@ func LoadEnvFile(path string) error {
 		if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") && len(value) >= 2 {
 			value = strings.Trim(value, "\"")
 		}
-		if err := os.Setenv(key, value); err != nil {
+		value, err = interpolate(value, strict, lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		defined[key] = value
+		if err := set(key, value); err != nil {
 			return err
 		}
 	}
@@ -49,24 +308,315 @@ func LoadEnvFile(path string) error {
 	return nil
 }
 
+// env looks up a configuration variable, returning an empty string when it is unset.
+type env func(key string) string
+
 // Load reads environment variables to build a Config value.
 func Load() (Config, error) {
+	return env(os.Getenv).load()
+}
+
+// LoadWithEnvFile builds a Config value from the environment overlaid with the variables of the
+// env file at path, without modifying the process environment. A missing file is ignored. Unlike
+// LoadFromEnvFile followed by Load, variables removed from the file since a previous call no longer
+// apply, so it suits reloading the configuration.
+func LoadWithEnvFile(path string) (Config, error) {
+	vars, err := ReadEnvFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, err
+	}
+	return env(func(key string) string {
+		if value, ok := vars[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}).load()
+}
+
+func (e env) load() (Config, error) {
 	cfg := Config{
-		GoogleAPIKey: os.Getenv("GOOGLE_MAPS_API_KEY"),
-		ServerPort:   os.Getenv("PORT"),
+		ServerPort:          e("PORT"),
+		GoogleClientID:      strings.TrimSpace(e("GOOGLE_MAPS_CLIENT_ID")),
+		GoogleSigningSecret: strings.TrimSpace(e("GOOGLE_MAPS_SIGNING_SECRET")),
+		GoogleChannel:       strings.TrimSpace(e("GOOGLE_MAPS_CHANNEL")),
+		TLSCertFile:         strings.TrimSpace(e("TLS_CERT_FILE")),
+		TLSKeyFile:          strings.TrimSpace(e("TLS_KEY_FILE")),
+		PprofAddr:           strings.TrimSpace(e("PPROF_ADDR")),
+
+		InboundSigningSecret:  strings.TrimSpace(e("INBOUND_SIGNING_SECRET")),
+		CacheSnapshotPath:     e("CACHE_SNAPSHOT_PATH"),
+		AddressBlocklist:      e.listEnv("ADDRESS_BLOCKLIST"),
+		AddressAllowlist:      e.listEnv("ADDRESS_ALLOWLIST"),
+		TrustedProxies:        e.listEnv("TRUSTED_PROXIES"),
+		DefaultCountry:        e.listEnv("DEFAULT_COUNTRY"),
+		AddressComponentOrder: e.listEnv("ADDRESS_COMPONENT_ORDER"),
+		HideSourceRoutes:      e.listEnv("HIDE_SOURCE_ROUTES"),
+		StatsDAddr:            strings.TrimSpace(e("STATSD_ADDR")),
+		AlertWebhookURL:       strings.TrimSpace(e("ALERT_WEBHOOK_URL")),
+		MetricsResetToken:     strings.TrimSpace(e("METRICS_RESET_TOKEN")),
+		CacheGraceToken:       strings.TrimSpace(e("CACHE_GRACE_TOKEN")),
+		StatsDPrefix:          strings.TrimSpace(e("STATSD_PREFIX")),
+
+		StaticDatasetPath:     strings.TrimSpace(e("STATIC_DATASET_PATH")),
+		CoordinateInputMode:   strings.ToLower(strings.TrimSpace(e("COORDINATE_INPUT_MODE"))),
+		ValidateResponseStyle: strings.ToLower(strings.TrimSpace(e("VALIDATE_RESPONSE_STYLE"))),
+		ResultTieBreak:        strings.ToLower(strings.TrimSpace(e("RESULT_TIE_BREAK"))),
+		AddressFormat:         strings.ToLower(strings.TrimSpace(e("ADDRESS_FORMAT"))),
 	}
 
 	if cfg.ServerPort == "" {
 		cfg.ServerPort = "8080"
 	}
 
-	if cfg.GoogleAPIKey == "" {
-		return Config{}, errors.New("GOOGLE_MAPS_API_KEY is required")
+	switch cfg.CoordinateInputMode {
+	case "":
+		cfg.CoordinateInputMode = "allow"
+	case "allow", "reject", "fallback":
+	default:
+		return Config{}, errors.New("COORDINATE_INPUT_MODE must be allow, reject or fallback")
+	}
+
+	switch cfg.ResultTieBreak {
+	case "":
+		cfg.ResultTieBreak = "first"
+	case "first", "address":
+	default:
+		return Config{}, errors.New("RESULT_TIE_BREAK must be first or address")
+	}
+
+	switch cfg.AddressFormat {
+	case "":
+		cfg.AddressFormat = "formatted"
+	case "formatted", "components":
+	default:
+		return Config{}, errors.New("ADDRESS_FORMAT must be formatted or components")
+	}
+
+	switch cfg.ValidateResponseStyle {
+	case "":
+		cfg.ValidateResponseStyle = "body"
+	case "body", "status":
+	default:
+		return Config{}, errors.New("VALIDATE_RESPONSE_STYLE must be body or status")
+	}
+
+	if cfg.PprofAddr == "" {
+		cfg.PprofAddr = "127.0.0.1:6060"
+	}
+
+	if cfg.CacheSnapshotPath == "" {
+		cfg.CacheSnapshotPath = "cache-snapshot.json"
+	}
+	if cfg.StatsDPrefix == "" {
+		cfg.StatsDPrefix = "apigo"
+	}
+
+	var err error
+	if cfg.GoogleAPIKey, err = e.apiKeySource().Secret(); err != nil {
+		return Config{}, fmt.Errorf("GOOGLE_MAPS_API_KEY_FILE: %w", err)
+	}
+	if cfg.EnablePprof, err = e.boolEnv("ENABLE_PPROF", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.CacheSnapshotEnabled, err = e.boolEnv("CACHE_SNAPSHOT_ENABLED", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ProviderTTLs, err = e.durationMapEnv("CACHE_TTL_BY_PROVIDER", strings.ToLower, false); err != nil {
+		return Config{}, err
+	}
+	if cfg.PrecisionTTLs, err = e.durationMapEnv("CACHE_TTL_BY_PRECISION", strings.ToUpper, false); err != nil {
+		return Config{}, err
+	}
+	// A zero route timeout removes the route's deadline, so it is allowed here.
+	if cfg.RouteTimeouts, err = e.durationMapEnv("ROUTE_TIMEOUTS", strings.TrimSpace, true); err != nil {
+		return Config{}, err
+	}
+	if cfg.CollisionGuard, err = e.boolEnv("CACHE_COLLISION_GUARD", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.HideSource, err = e.boolEnv("HIDE_SOURCE", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.StrictQueryParams, err = e.boolEnv("STRICT_QUERY_PARAMS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ResponseFreshness, err = e.boolEnv("RESPONSE_FRESHNESS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ProblemJSON, err = e.boolEnv("PROBLEM_JSON_ERRORS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ResponseEnvelope, err = e.boolEnv("RESPONSE_ENVELOPE", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.DebugResponses, err = e.boolEnv("DEBUG_RESPONSES", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ExplainResponses, err = e.boolEnv("EXPLAIN_RESPONSES", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.DemoPage, err = e.boolEnv("DEMO_PAGE_ENABLED", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.DebugMaxBytes, err = e.intEnv("DEBUG_MAX_BYTES", 16<<10); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinAddressLength, err = e.intEnv("MIN_ADDRESS_LENGTH", 3); err != nil {
+		return Config{}, err
+	}
+	if cfg.CoordinateDecimals, err = e.intEnv("COORDINATE_DECIMALS", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.CanonicalCacheSize, err = e.intEnv("CANONICAL_CACHE_SIZE", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.CacheShards, err = e.intEnv("CACHE_SHARDS", 16); err != nil {
+		return Config{}, err
+	}
+	if cfg.CacheShards == 0 || cfg.CacheShards&(cfg.CacheShards-1) != 0 {
+		return Config{}, errors.New("CACHE_SHARDS must be a positive power of two")
+	}
+	if cfg.CacheChurnLimit, err = e.intEnv("CACHE_CHURN_LIMIT", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.CacheChurnWindow, err = e.durationEnv("CACHE_CHURN_WINDOW", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.CacheStatsWindow, err = e.durationEnv("CACHE_STATS_WINDOW", 5*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.InboundSigningWindow, err = e.durationEnv("INBOUND_SIGNING_WINDOW", 5*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxLookupDuration, err = e.optionalDurationEnv("MAX_LOOKUP_DURATION", 10*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboundIdleConnTimeout, err = e.durationEnv("OUTBOUND_IDLE_CONN_TIMEOUT", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboundMaxIdleConnsPerHost, err = e.intEnv("OUTBOUND_MAX_IDLE_CONNS_PER_HOST", 10); err != nil {
+		return Config{}, err
+	}
+	if cfg.OutboundKeepAlive, err = e.durationEnv("OUTBOUND_KEEP_ALIVE", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.DailyUpstreamCap, err = e.intEnv("DAILY_UPSTREAM_CAP", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.DailyCapReset, err = e.timeOfDayEnv("DAILY_CAP_RESET_UTC"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxInflightLookups, err = e.intEnv("MAX_INFLIGHT_LOOKUPS", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.AdaptiveRateMax, err = e.floatEnv("ADAPTIVE_RATE_MAX", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.AdaptiveRateMin, err = e.floatEnv("ADAPTIVE_RATE_MIN", 1); err != nil {
+		return Config{}, err
+	}
+	if cfg.AdaptiveRateMax > 0 && (cfg.AdaptiveRateMin <= 0 || cfg.AdaptiveRateMin > cfg.AdaptiveRateMax) {
+		return Config{}, errors.New("ADAPTIVE_RATE_MIN must be positive and at most ADAPTIVE_RATE_MAX")
+	}
+	if cfg.ProviderTimeout, err = e.optionalDurationEnv("PROVIDER_TIMEOUT", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxProviderAttempts, err = e.intEnv("MAX_PROVIDER_ATTEMPTS", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.ChaosFailureRate, err = e.rateEnv("CHAOS_FAILURE_RATE", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.ChaosLatency, err = e.optionalDurationEnv("CHAOS_LATENCY", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.FailFastDeniedThreshold, err = e.intEnv("FAIL_FAST_DENIED_THRESHOLD", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.AlertThreshold, err = e.intEnv("ALERT_THRESHOLD", 5); err != nil {
+		return Config{}, err
+	}
+	if cfg.AlertWindow, err = e.durationEnv("ALERT_WINDOW", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.AlertCooldown, err = e.durationEnv("ALERT_COOLDOWN", 10*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.StatsDTags, err = e.boolEnv("STATSD_TAGS", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.LogSampleRate, err = e.rateEnv("LOG_SAMPLE_RATE", 0.01); err != nil {
+		return Config{}, err
+	}
+	if cfg.LogSlowThreshold, err = e.durationEnv("LOG_SLOW_THRESHOLD", time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.ReadinessTimeout, err = e.durationEnv("READINESS_TIMEOUT", time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.FailureCacheTTL, err = e.optionalDurationEnv("FAILURE_CACHE_TTL", 5*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.NoResultsCacheTTL, err = e.optionalDurationEnv("NO_RESULTS_CACHE_TTL", time.Minute); err != nil {
+		return Config{}, err
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return Config{}, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if (cfg.GoogleClientID == "") != (cfg.GoogleSigningSecret == "") {
+		return Config{}, errors.New("GOOGLE_MAPS_CLIENT_ID and GOOGLE_MAPS_SIGNING_SECRET must be set together")
+	}
+
+	if cfg.GoogleAPIKey == "" && cfg.GoogleClientID == "" {
+		return Config{}, errors.New("GOOGLE_MAPS_API_KEY or GOOGLE_MAPS_API_KEY_FILE is required")
 	}
 
 	return cfg, nil
 }
 
+// TLSEnabled reports whether the server should be served over HTTPS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// interpolate expands ${VAR} references in value from the environment and turns $$ into a literal
+// $. Any other $ is kept as is. In strict mode a reference to an unset variable is an error.
+func interpolate(value string, strict bool, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", errors.New("unterminated ${ in env file value")
+			}
+			name := value[i+2 : i+2+end]
+			resolved, ok := lookup(name)
+			if !ok && strict {
+				return "", fmt.Errorf("undefined variable %s in env file value", name)
+			}
+			b.WriteString(resolved)
+			i += end + 2
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
 // LoadFromEnvFile first attempts to read an env file and ignores missing file errors.
 func LoadFromEnvFile(path string) error {
 	err := LoadEnvFile(path)
@@ -75,3 +625,129 @@ func LoadFromEnvFile(path string) error {
 	}
 	return nil
 }
+
+// boolEnv parses a boolean environment variable, returning fallback when it is unset.
+func (e env) boolEnv(key string, fallback bool) (bool, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New(key + " must be a boolean")
+	}
+	return value, nil
+}
+
+// durationEnv parses a time.Duration environment variable, returning fallback when it is unset.
+func (e env) durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return 0, errors.New(key + " must be a positive duration")
+	}
+	return value, nil
+}
+
+// listEnv splits a semicolon-separated environment variable into its non-empty entries.
+func (e env) listEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(e(key), ";") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// intEnv parses a non-negative integer environment variable, returning fallback when it is unset.
+func (e env) intEnv(key string, fallback int) (int, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, errors.New(key + " must be a non-negative integer")
+	}
+	return value, nil
+}
+
+// optionalDurationEnv parses a non-negative time.Duration environment variable where zero disables
+// the related feature, returning fallback when it is unset.
+func (e env) optionalDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		return 0, errors.New(key + " must be a non-negative duration")
+	}
+	return value, nil
+}
+
+// rateEnv parses a fraction between 0 and 1, returning fallback when it is unset.
+func (e env) rateEnv(key string, fallback float64) (float64, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 || value > 1 {
+		return 0, errors.New(key + " must be a number between 0 and 1")
+	}
+	return value, nil
+}
+
+// floatEnv parses a non-negative number, returning fallback when it is unset.
+func (e env) floatEnv(key string, fallback float64) (float64, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New(key + " must be a non-negative number")
+	}
+	return value, nil
+}
+
+// timeOfDayEnv parses an HH:MM time of day into the duration since midnight, returning zero when it
+// is unset.
+func (e env) timeOfDayEnv(key string) (time.Duration, error) {
+	raw := strings.TrimSpace(e(key))
+	if raw == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, errors.New(key + " must be a time of day formatted as HH:MM")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// durationMapEnv parses semicolon-separated NAME=duration pairs, passing each name through
+// normalize. Durations must be positive, or non-negative when allowZero is set.
+func (e env) durationMapEnv(key string, normalize func(string) string, allowZero bool) (map[string]time.Duration, error) {
+	entries := e.listEnv(key)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		name, raw, ok := strings.Cut(entry, "=")
+		value, err := time.ParseDuration(strings.TrimSpace(raw))
+		switch {
+		case !ok || err != nil || value < 0:
+			return nil, errors.New(key + " must be a list of NAME=duration pairs with non-negative durations")
+		case value == 0 && !allowZero:
+			return nil, errors.New(key + " must be a list of NAME=duration pairs with positive durations")
+		}
+		values[normalize(strings.TrimSpace(name))] = value
+	}
+	return values, nil
+}
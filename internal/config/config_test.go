@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envOf returns an env reading from vars, with the Google API key every valid configuration needs
+// unless vars sets it.
+func envOf(vars map[string]string) env {
+	return func(key string) string {
+		if value, ok := vars[key]; ok {
+			return value
+		}
+		if key == "GOOGLE_MAPS_API_KEY" {
+			return "test-key"
+		}
+		return ""
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	tests := []struct {
+		name        string
+		vars        map[string]string
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{name: "disabled", vars: map[string]string{}},
+		{name: "enabled", vars: map[string]string{"TLS_CERT_FILE": "cert.pem", "TLS_KEY_FILE": "key.pem"}, wantEnabled: true},
+		{name: "certificate only", vars: map[string]string{"TLS_CERT_FILE": "cert.pem"}, wantErr: true},
+		{name: "key only", vars: map[string]string{"TLS_KEY_FILE": "key.pem"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := envOf(tt.vars).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if cfg.TLSEnabled() != tt.wantEnabled {
+				t.Errorf("TLSEnabled = %v, want %v", cfg.TLSEnabled(), tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestLoadWithEnvFileReparsesTheFile(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "test-key")
+	t.Setenv("ADDRESS_BLOCKLIST", "")
+	path := filepath.Join(t.TempDir(), ".env")
+
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{name: "initial", file: "ADDRESS_BLOCKLIST=rua proibida\n", want: []string{"rua proibida"}},
+		{name: "edited", file: "ADDRESS_BLOCKLIST=rua proibida;avenida fechada\n", want: []string{"rua proibida", "avenida fechada"}},
+		{name: "removed", file: "PORT=8080\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatalf("write env file: %v", err)
+			}
+			cfg, err := LoadWithEnvFile(path)
+			if err != nil {
+				t.Fatalf("LoadWithEnvFile: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.AddressBlocklist, tt.want) {
+				t.Errorf("AddressBlocklist = %q, want %q", cfg.AddressBlocklist, tt.want)
+			}
+		})
+	}
+	if _, ok := os.LookupEnv("ADDRESS_BLOCKLIST"); !ok || os.Getenv("ADDRESS_BLOCKLIST") != "" {
+		t.Error("LoadWithEnvFile modified the process environment")
+	}
+}
+
+func TestLoadValidateResponseStyle(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: "body"},
+		{value: "body", want: "body"},
+		{value: " Status ", want: "status"},
+		{value: "empty", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := envOf(map[string]string{"VALIDATE_RESPONSE_STYLE": tt.value}).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if cfg.ValidateResponseStyle != tt.want {
+				t.Errorf("ValidateResponseStyle = %q, want %q", cfg.ValidateResponseStyle, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProviderTTLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "providers", value: "Nominatim=168h; google=1h", want: map[string]time.Duration{"nominatim": 168 * time.Hour, "google": time.Hour}},
+		{name: "missing duration", value: "nominatim", wantErr: true},
+		{name: "invalid duration", value: "nominatim=a week", wantErr: true},
+		{name: "negative duration", value: "google=-1h", wantErr: true},
+		{name: "zero duration", value: "static=24h;google=0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := envOf(map[string]string{"CACHE_TTL_BY_PROVIDER": tt.value}).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "CACHE_TTL_BY_PROVIDER") {
+				t.Errorf("load error = %v, want it to name CACHE_TTL_BY_PROVIDER", err)
+			}
+			if !reflect.DeepEqual(cfg.ProviderTTLs, tt.want) {
+				t.Errorf("ProviderTTLs = %v, want %v", cfg.ProviderTTLs, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadZeroDurations(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "CACHE_TTL_BY_PROVIDER", wantErr: true},
+		{key: "CACHE_TTL_BY_PRECISION", wantErr: true},
+		{key: "ROUTE_TIMEOUTS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			_, err := envOf(map[string]string{tt.key: "google=0"}).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("load error = %v, want it to name %s", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestLoadAddressFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      map[string]string
+		want      string
+		wantOrder []string
+		wantErr   bool
+	}{
+		{name: "default", vars: map[string]string{}, want: "formatted"},
+		{name: "components", vars: map[string]string{"ADDRESS_FORMAT": "Components"}, want: "components"},
+		{name: "components with an order", vars: map[string]string{"ADDRESS_FORMAT": "components", "ADDRESS_COMPONENT_ORDER": "route; locality;country"},
+			want: "components", wantOrder: []string{"route", "locality", "country"}},
+		{name: "unknown", vars: map[string]string{"ADDRESS_FORMAT": "short"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := envOf(tt.vars).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if cfg.AddressFormat != tt.want || !reflect.DeepEqual(cfg.AddressComponentOrder, tt.wantOrder) {
+				t.Errorf("address format %q with order %q, want %q with %q", cfg.AddressFormat, cfg.AddressComponentOrder, tt.want, tt.wantOrder)
+			}
+		})
+	}
+}
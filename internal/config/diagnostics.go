@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Diagnostics summarizes the effective configuration for the startup log. Secrets are never
+// included: the API key is reduced to a fingerprint and other secrets to whether they are set.
+type Diagnostics struct {
+	Port      string   `json:"port"`
+	TLS       bool     `json:"tls"`
+	Providers []string `json:"providers"`
+
+	APIKey               string `json:"api_key,omitempty"`
+	PremiumClientID      string `json:"premium_client_id,omitempty"`
+	SigningSecretSet     bool   `json:"signing_secret_set"`
+	InboundSigningSecret bool   `json:"inbound_signing_secret_set"`
+	MetricsResetEnabled  bool   `json:"metrics_reset_enabled"`
+	CacheGraceEnabled    bool   `json:"cache_grace_enabled"`
+
+	CacheTTL          string            `json:"cache_ttl"`
+	ProviderTTLs      map[string]string `json:"provider_ttls,omitempty"`
+	PrecisionTTLs     map[string]string `json:"precision_ttls,omitempty"`
+	CacheSnapshot     bool              `json:"cache_snapshot"`
+	FailureCacheTTL   string            `json:"failure_cache_ttl"`
+	NoResultsCacheTTL string            `json:"no_results_cache_ttl"`
+
+	MaxLookupDuration   string `json:"max_lookup_duration"`
+	ProviderTimeout     string `json:"provider_timeout"`
+	MaxProviderAttempts int    `json:"max_provider_attempts"`
+	MaxInflightLookups  int    `json:"max_inflight_lookups"`
+	DailyUpstreamCap    int    `json:"daily_upstream_cap"`
+	// AdaptiveRate is the "min-max" requests per second range of the adaptive limiter, if enabled.
+	AdaptiveRate string `json:"adaptive_rate,omitempty"`
+
+	Middleware []string `json:"middleware"`
+	Metrics    string   `json:"metrics,omitempty"`
+	Pprof      string   `json:"pprof,omitempty"`
+	Chaos      bool     `json:"chaos"`
+}
+
+// Diagnostics returns the redacted summary of c. Settings decided outside the configuration, such
+// as the cache TTL and the middleware chain, are left for the caller to fill in.
+func (c Config) Diagnostics() Diagnostics {
+	providers := []string{"google"}
+	if c.StaticDatasetPath != "" {
+		providers = []string{"static", "google"}
+	}
+
+	d := Diagnostics{
+		Port:      c.ServerPort,
+		TLS:       c.TLSEnabled(),
+		Providers: providers,
+
+		APIKey:               Fingerprint(c.GoogleAPIKey),
+		PremiumClientID:      c.GoogleClientID,
+		SigningSecretSet:     c.GoogleSigningSecret != "",
+		InboundSigningSecret: c.InboundSigningSecret != "",
+		MetricsResetEnabled:  c.MetricsResetToken != "",
+		CacheGraceEnabled:    c.CacheGraceToken != "",
+
+		CacheSnapshot:     c.CacheSnapshotEnabled,
+		FailureCacheTTL:   c.FailureCacheTTL.String(),
+		NoResultsCacheTTL: c.NoResultsCacheTTL.String(),
+
+		MaxLookupDuration:   c.MaxLookupDuration.String(),
+		ProviderTimeout:     c.ProviderTimeout.String(),
+		MaxProviderAttempts: c.MaxProviderAttempts,
+		MaxInflightLookups:  c.MaxInflightLookups,
+		DailyUpstreamCap:    c.DailyUpstreamCap,
+
+		Metrics: c.StatsDAddr,
+		Chaos:   c.ChaosFailureRate > 0 || c.ChaosLatency > 0,
+	}
+	if c.AdaptiveRateMax > 0 {
+		d.AdaptiveRate = fmt.Sprintf("%g-%g", c.AdaptiveRateMin, c.AdaptiveRateMax)
+	}
+	d.ProviderTTLs = durationStrings(c.ProviderTTLs)
+	d.PrecisionTTLs = durationStrings(c.PrecisionTTLs)
+	if c.EnablePprof {
+		d.Pprof = c.PprofAddr
+	}
+	return d
+}
+
+// Fingerprint identifies a secret in logs by its last four characters. Secrets too short to hide
+// anything are fully masked.
+func Fingerprint(secret string) string {
+	const visible = 4
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 2*visible {
+		return "****"
+	}
+	return "****" + secret[len(secret)-visible:]
+}
+
+// durationStrings formats the durations of m, returning nil for an empty map.
+func durationStrings(m map[string]time.Duration) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	formatted := make(map[string]string, len(m))
+	for name, d := range m {
+		formatted[name] = d.String()
+	}
+	return formatted
+}
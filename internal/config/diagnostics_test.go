@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsRedactsSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"GOOGLE_MAPS_API_KEY":        "AIzaSyD-0123456789abcdefWXYZ",
+		"GOOGLE_MAPS_CLIENT_ID":      "gme-partner",
+		"GOOGLE_MAPS_SIGNING_SECRET": "vNIXE0xscrmjlyV-12Nj_BvUPaw=",
+		"INBOUND_SIGNING_SECRET":     "inbound-hmac-secret",
+		"METRICS_RESET_TOKEN":        "reset-bearer-token",
+		"CACHE_GRACE_TOKEN":          "grace-bearer-token",
+	}
+	cfg, err := envOf(secrets).load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	raw, err := json.Marshal(cfg.Diagnostics())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	line := string(raw)
+
+	for name, value := range secrets {
+		if name == "GOOGLE_MAPS_CLIENT_ID" {
+			continue
+		}
+		if strings.Contains(line, value) {
+			t.Errorf("diagnostics leak %s: %s", name, line)
+		}
+	}
+	var d map[string]any
+	if err := json.Unmarshal(raw, &d); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := map[string]any{
+		"api_key":                    "****WXYZ",
+		"premium_client_id":          "gme-partner",
+		"signing_secret_set":         true,
+		"inbound_signing_secret_set": true,
+		"metrics_reset_enabled":      true,
+		"cache_grace_enabled":        true,
+	}
+	for field, value := range want {
+		if d[field] != value {
+			t.Errorf("%s = %v, want %v", field, d[field], value)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{secret: "", want: ""},
+		{secret: "abc", want: "****"},
+		{secret: "abcdefgh", want: "****"},
+		{secret: "abcdefghi", want: "****fghi"},
+		{secret: "AIzaSyD-0123456789abcdefWXYZ", want: "****WXYZ"},
+	}
+	for _, tt := range tests {
+		if got := Fingerprint(tt.secret); got != tt.want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", tt.secret, got, tt.want)
+		}
+	}
+}
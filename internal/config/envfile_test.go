@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeEnvFile writes content to a .env file in a temporary directory and returns its path.
+func writeEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write env file: %v", err)
+	}
+	return path
+}
+
+func TestReadEnvFileLineEndings(t *testing.T) {
+	want := map[string]string{"GOOGLE_MAPS_API_KEY": "AIzaSyD-key", "PORT": "8080"}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "LF", content: "GOOGLE_MAPS_API_KEY=AIzaSyD-key\nPORT=8080\n"},
+		{name: "BOM", content: "\ufeffGOOGLE_MAPS_API_KEY=AIzaSyD-key\nPORT=8080\n"},
+		{name: "CRLF", content: "GOOGLE_MAPS_API_KEY=AIzaSyD-key\r\nPORT=8080\r\n"},
+		{name: "BOM and CRLF", content: "\ufeffGOOGLE_MAPS_API_KEY=AIzaSyD-key\r\n# comment\r\n\r\nPORT=8080"},
+		{name: "quoted with CRLF", content: "\ufeffGOOGLE_MAPS_API_KEY=\"AIzaSyD-key\"\r\nPORT=8080\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := ReadEnvFile(writeEnvFile(t, tt.content))
+			if err != nil {
+				t.Fatalf("ReadEnvFile: %v", err)
+			}
+			if !reflect.DeepEqual(vars, want) {
+				t.Errorf("vars = %q, want %q", vars, want)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFileWithBOMSetsTheFirstKey(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "")
+	if err := LoadEnvFile(writeEnvFile(t, "\ufeffGOOGLE_MAPS_API_KEY=AIzaSyD-key\r\n")); err != nil {
+		t.Fatalf("LoadEnvFile: %v", err)
+	}
+	if got := os.Getenv("GOOGLE_MAPS_API_KEY"); got != "AIzaSyD-key" {
+		t.Fatalf("GOOGLE_MAPS_API_KEY = %q, want AIzaSyD-key", got)
+	}
+}
+
+func TestEnvFileInterpolation(t *testing.T) {
+	t.Setenv("APIGO_TEST_HOST", "geo.internal")
+	t.Setenv("APIGO_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "variables from the file",
+			content: "PORT=8443\nBASE_URL=https://${APIGO_TEST_HOST}:${PORT}/v1\n",
+			want:    map[string]string{"PORT": "8443", "BASE_URL": "https://geo.internal:8443/v1"},
+		},
+		{
+			name:    "later definitions are not visible",
+			content: "BASE_URL=https://${APIGO_TEST_HOST}:${LATE_PORT}\nLATE_PORT=80\n",
+			want:    map[string]string{"BASE_URL": "https://geo.internal:", "LATE_PORT": "80"},
+		},
+		{
+			name:    "escaped dollar",
+			content: "PRICE=$$5 per ${APIGO_TEST_HOST}\nLITERAL=$$${APIGO_TEST_HOST}\n",
+			want:    map[string]string{"PRICE": "$5 per geo.internal", "LITERAL": "$geo.internal"},
+		},
+		{
+			name:    "bare dollar kept",
+			content: "PATTERN=re:^rua$\nSHELL_STYLE=$HOME\n",
+			want:    map[string]string{"PATTERN": "re:^rua$", "SHELL_STYLE": "$HOME"},
+		},
+		{
+			name:    "undefined expands to empty",
+			content: "URL=https://${APIGO_TEST_UNDEFINED}/x\nEMPTY=[${APIGO_TEST_EMPTY}]\n",
+			want:    map[string]string{"URL": "https:///x", "EMPTY": "[]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := ReadEnvFile(writeEnvFile(t, tt.content))
+			if err != nil {
+				t.Fatalf("ReadEnvFile: %v", err)
+			}
+			if !reflect.DeepEqual(vars, tt.want) {
+				t.Errorf("vars = %q, want %q", vars, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFileStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "defined", content: "APIGO_TEST_A=1\nAPIGO_TEST_B=${APIGO_TEST_A}2\n"},
+		{name: "set but empty", content: "APIGO_TEST_A=\nAPIGO_TEST_B=${APIGO_TEST_A}2\n"},
+		{name: "undefined", content: "APIGO_TEST_B=${APIGO_TEST_UNDEFINED}\n", wantErr: true},
+		{name: "unterminated", content: "APIGO_TEST_B=${APIGO_TEST_A\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setenv restores the variables the file sets once the test ends.
+			t.Setenv("APIGO_TEST_A", "")
+			t.Setenv("APIGO_TEST_B", "")
+			os.Unsetenv("APIGO_TEST_A")
+			os.Unsetenv("APIGO_TEST_B")
+
+			err := LoadEnvFileStrict(writeEnvFile(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadEnvFileStrict error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
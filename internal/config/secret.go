@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretSource provides a secret, such as the Google Maps API key. It lets secrets come from
+// places other than plain environment variables; a secret manager can be supported by adding an
+// implementation.
+type SecretSource interface {
+	Secret() (string, error)
+}
+
+// EnvSecret reads a secret from the environment variable it names.
+type EnvSecret string
+
+// Secret returns the variable's value, or an empty string when it is unset.
+func (e EnvSecret) Secret() (string, error) {
+	return os.Getenv(string(e)), nil
+}
+
+// FileSecret reads a secret from the file at the path it holds, as mounted by Docker or
+// Kubernetes secrets. Surrounding whitespace, including the trailing newline, is trimmed.
+type FileSecret string
+
+// Secret returns the file's trimmed contents.
+func (f FileSecret) Secret() (string, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", string(f))
+	}
+	return secret, nil
+}
+
+// lookupSecret reads a secret from a variable of the environment the configuration is loaded
+// from, which may include an env file.
+type lookupSecret struct {
+	env env
+	key string
+}
+
+func (l lookupSecret) Secret() (string, error) {
+	return l.env(l.key), nil
+}
+
+// apiKeySource returns where the Google Maps API key is read from: the file named by
+// GOOGLE_MAPS_API_KEY_FILE when set, otherwise GOOGLE_MAPS_API_KEY.
+func (e env) apiKeySource() SecretSource {
+	if path := strings.TrimSpace(e("GOOGLE_MAPS_API_KEY_FILE")); path != "" {
+		return FileSecret(path)
+	}
+	return lookupSecret{env: e, key: "GOOGLE_MAPS_API_KEY"}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSecret(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		contents string
+		missing  bool
+		want     string
+		wantErr  bool
+	}{
+		{name: "trimmed", contents: "  AIzaSyD-from-file\n", want: "AIzaSyD-from-file"},
+		{name: "empty", contents: " \n", wantErr: true},
+		{name: "missing", missing: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+					t.Fatalf("write secret: %v", err)
+				}
+			}
+			got, err := FileSecret(path).Secret()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Secret() = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte("AIzaSyD-from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "inline", vars: map[string]string{"GOOGLE_MAPS_API_KEY": "AIzaSyD-inline"}, want: "AIzaSyD-inline"},
+		{name: "file", vars: map[string]string{"GOOGLE_MAPS_API_KEY": "", "GOOGLE_MAPS_API_KEY_FILE": path}, want: "AIzaSyD-from-file"},
+		{name: "file takes precedence", vars: map[string]string{"GOOGLE_MAPS_API_KEY": "AIzaSyD-inline", "GOOGLE_MAPS_API_KEY_FILE": path}, want: "AIzaSyD-from-file"},
+		{name: "missing file", vars: map[string]string{"GOOGLE_MAPS_API_KEY": "AIzaSyD-inline", "GOOGLE_MAPS_API_KEY_FILE": path + ".missing"}, wantErr: true},
+		{name: "neither", vars: map[string]string{"GOOGLE_MAPS_API_KEY": ""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := envOf(tt.vars).load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, want error %v", err, tt.wantErr)
+			}
+			if cfg.GoogleAPIKey != tt.want {
+				t.Errorf("GoogleAPIKey = %q, want %q", cfg.GoogleAPIKey, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveSuccessRun is how many consecutive successful calls raise the rate by one step.
+	adaptiveSuccessRun = 10
+	// adaptiveSteps is how many steps it takes to climb from the minimum to the maximum rate.
+	adaptiveSteps = 20
+)
+
+// AdaptiveProvider decorates a provider to pace its calls at a rate that adapts to the upstream
+// quota, AIMD-style: a quota error halves the rate and every run of consecutive successes raises it
+// by a fixed step, always within the configured bounds. Calls wait for their slot instead of
+// failing, so bursts are smoothed out rather than rejected, unless the wait would outlast the
+// caller's deadline.
+type AdaptiveProvider struct {
+	inner   Provider
+	minRate float64
+	maxRate float64
+
+	mu        sync.Mutex
+	rate      float64
+	next      time.Time
+	successes int
+}
+
+// NewAdaptiveProvider wraps inner so that it is called at most maxRate times per second, slowing
+// down to as little as minRate per second while the upstream reports quota errors. It starts at
+// maxRate.
+func NewAdaptiveProvider(inner Provider, minRate, maxRate float64) *AdaptiveProvider {
+	return &AdaptiveProvider{inner: inner, minRate: minRate, maxRate: maxRate, rate: maxRate}
+}
+
+// Name reports the name of the decorated provider.
+func (p *AdaptiveProvider) Name() string {
+	return p.inner.Name()
+}
+
+// Unwrap returns the decorated provider.
+func (p *AdaptiveProvider) Unwrap() Provider {
+	return p.inner
+}
+
+// Ping forwards to the decorated provider's readiness check. Providers without one are always
+// reachable.
+func (p *AdaptiveProvider) Ping(ctx context.Context) error {
+	if pinger, ok := pingerOf(p.inner); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Rate reports the number of calls per second currently permitted.
+func (p *AdaptiveProvider) Rate() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rate
+}
+
+// Geocode waits for the next slot at the current rate, delegates to the decorated provider and
+// adjusts the rate to its outcome.
+func (p *AdaptiveProvider) Geocode(ctx context.Context, address string) (Result, error) {
+	if err := p.wait(ctx); err != nil {
+		return Result{}, err
+	}
+	result, err := p.inner.Geocode(ctx, address)
+	p.observe(err)
+	return result, err
+}
+
+// GeocodeFiltered paces a filtered lookup like Geocode. The decorated provider answers
+// ErrNoResults if it cannot apply filters.
+func (p *AdaptiveProvider) GeocodeFiltered(ctx context.Context, address, components string) (Result, error) {
+	if err := p.wait(ctx); err != nil {
+		return Result{}, err
+	}
+	result, err := query{address: address, components: components}.geocode(ctx, p.inner)
+	p.observe(err)
+	return result, err
+}
+
+// TimeZone paces a time zone lookup like Geocode, so both APIs share the rate of the quota they
+// draw from. The decorated provider answers ErrNoResults if it has no time zones.
+func (p *AdaptiveProvider) TimeZone(ctx context.Context, lat, lng float64, at time.Time) (TimeZone, error) {
+	if err := p.wait(ctx); err != nil {
+		return TimeZone{}, err
+	}
+	tz, err := timeZoneOf(ctx, p.inner, lat, lng, at)
+	p.observe(err)
+	return tz, err
+}
+
+// wait blocks until the next slot at the current rate, or until ctx is done. A caller whose slot
+// would come after its deadline fails right away with ErrOverloaded instead of queuing, and a
+// caller that gives up while queued hands its slot back so the callers behind it are not delayed
+// by a call that never happens.
+func (p *AdaptiveProvider) wait(ctx context.Context) error {
+	deadline, _ := ctx.Deadline()
+	wait, interval, ok := p.reserve(time.Now(), deadline)
+	if !ok {
+		return ErrOverloaded
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		p.unreserve(interval)
+		return ctx.Err()
+	}
+}
+
+// reserve claims the next free slot and returns how long the caller must wait for it and the
+// interval it took up. No slot is claimed when it would come after a non-zero deadline.
+func (p *AdaptiveProvider) reserve(now, deadline time.Time) (time.Duration, time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	if !deadline.IsZero() && slot.After(deadline) {
+		return 0, 0, false
+	}
+	interval := time.Duration(float64(time.Second) / p.rate)
+	p.next = slot.Add(interval)
+	return slot.Sub(now), interval, true
+}
+
+// unreserve gives back a slot claimed by reserve that was not used.
+func (p *AdaptiveProvider) unreserve(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next = p.next.Add(-interval)
+}
+
+// observe halves the rate on an upstream quota error and raises it by one step after each run of
+// successes. ErrNoResults means the upstream answered, so it counts as a success; other errors,
+// including the local daily cap, leave the rate alone.
+func (p *AdaptiveProvider) observe(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case err == nil || errors.Is(err, ErrNoResults):
+		p.successes++
+		if p.successes >= adaptiveSuccessRun {
+			p.successes = 0
+			p.rate = min(p.maxRate, p.rate+(p.maxRate-p.minRate)/adaptiveSteps)
+		}
+	case ErrorCategory(err) == CategoryQuota && !errors.Is(err, ErrQuotaCapReached):
+		p.successes = 0
+		p.rate = max(p.minRate, p.rate/2)
+	}
+}
@@ -0,0 +1,177 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveProviderRate(t *testing.T) {
+	quota := &APIStatusError{API: "google maps api", Status: "OVER_QUERY_LIMIT"}
+	successes := func(n int) []error { return make([]error, n) }
+	repeat := func(err error, n int) []error {
+		errs := make([]error, n)
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	tests := []struct {
+		name     string
+		outcomes []error
+		want     float64
+	}{
+		{name: "starts at the maximum", want: 100},
+		{name: "quota error halves the rate", outcomes: []error{quota}, want: 50},
+		{name: "429 is a quota error", outcomes: []error{&UpstreamStatusError{API: "google maps api", StatusCode: http.StatusTooManyRequests}}, want: 50},
+		{name: "never below the minimum", outcomes: repeat(quota, 10), want: 10},
+		{name: "a run of successes raises one step", outcomes: append(repeat(quota, 10), successes(adaptiveSuccessRun)...), want: 14.5},
+		{name: "short runs do not raise", outcomes: append(repeat(quota, 10), successes(adaptiveSuccessRun-1)...), want: 10},
+		{name: "a quota error restarts the run", outcomes: append(append(append(repeat(quota, 10), successes(adaptiveSuccessRun-1)...), quota), successes(1)...), want: 10},
+		{name: "no results counts as success", outcomes: append([]error{quota}, repeat(ErrNoResults, adaptiveSuccessRun)...), want: 54.5},
+		{name: "recovers up to the maximum", outcomes: append(repeat(quota, 10), successes(adaptiveSuccessRun*adaptiveSteps*2)...), want: 100},
+		{name: "other errors leave the rate alone", outcomes: []error{
+			errors.New("connection reset"), ErrQuotaCapReached, &UpstreamStatusError{API: "google maps api", StatusCode: http.StatusBadGateway},
+		}, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAdaptiveProvider(&stubProvider{name: "google"}, 10, 100)
+			for _, err := range tt.outcomes {
+				p.observe(err)
+			}
+			if got := p.Rate(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("rate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveProviderObservesDecoratedCalls(t *testing.T) {
+	var answer error
+	inner := &stubProvider{name: "google", answer: func(context.Context, string) (Result, error) { return Result{}, answer }}
+	p := NewAdaptiveProvider(inner, 1000, 100000)
+
+	answer = &APIStatusError{API: "google maps api", Status: "OVER_QUERY_LIMIT"}
+	for i := 0; i < 3; i++ {
+		p.Geocode(context.Background(), "praça da sé")
+	}
+	if got := p.Rate(); got != 12500 {
+		t.Fatalf("rate after quota errors = %v, want 12500", got)
+	}
+
+	answer = nil
+	for i := 0; i < adaptiveSuccessRun; i++ {
+		p.Geocode(context.Background(), "praça da sé")
+	}
+	if got := p.Rate(); got != 12500+(100000-1000)/adaptiveSteps {
+		t.Errorf("rate after successes = %v, want one step higher", got)
+	}
+	if got := inner.calls.Load(); got != 3+adaptiveSuccessRun {
+		t.Errorf("inner calls = %d, want %d", got, 3+adaptiveSuccessRun)
+	}
+}
+
+func TestAdaptiveProviderPacesCalls(t *testing.T) {
+	p := NewAdaptiveProvider(&stubProvider{name: "google", answer: answerWith(Result{})}, 20, 20)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := p.Geocode(context.Background(), "praça da sé"); err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+	}
+	// The first call goes right away and the next two wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 calls at 20/s took %s, want at least 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Geocode(ctx, "praça da sé"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Geocode with a canceled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestAdaptiveProviderReleasesCanceledSlots(t *testing.T) {
+	p := NewAdaptiveProvider(&stubProvider{name: "google", answer: answerWith(Result{})}, 10, 10)
+	if _, err := p.Geocode(context.Background(), "praça da sé"); err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+
+	// A burst of 20 callers queues up to two seconds ahead at 10/s, and all of them give up.
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Geocode(ctx, "praça da sé")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	start := time.Now()
+	if _, err := p.Geocode(context.Background(), "praça da sé"); err != nil {
+		t.Fatalf("Geocode after the burst: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("next caller waited %s behind canceled callers, want at most one interval", elapsed)
+	}
+}
+
+func TestAdaptiveProviderFailsFastPastTheDeadline(t *testing.T) {
+	inner := &stubProvider{name: "google", answer: answerWith(Result{})}
+	p := NewAdaptiveProvider(inner, 1, 1)
+	if _, err := p.Geocode(context.Background(), "praça da sé"); err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.Geocode(ctx, "praça da sé"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Geocode whose slot is past the deadline = %v, want ErrOverloaded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("rejection took %s, want it right away", elapsed)
+	}
+	if got := inner.calls.Load(); got != 1 {
+		t.Errorf("inner calls = %d, want 1", got)
+	}
+
+	// The rejected caller claimed no slot, so the next one is served a second after the first.
+	if wait, _, _ := p.reserve(time.Now(), time.Time{}); wait > time.Second {
+		t.Errorf("next slot in %s, want within a second", wait)
+	}
+}
+
+func TestAdaptiveProviderForwardsPing(t *testing.T) {
+	down := errors.New("connection refused")
+	tests := []struct {
+		name  string
+		inner Provider
+		want  error
+	}{
+		{name: "pinger", inner: &pingingProvider{stubProvider: stubProvider{name: "google"}, ping: func(context.Context) error { return down }}, want: down},
+		{name: "healthy pinger", inner: &pingingProvider{stubProvider: stubProvider{name: "google"}, ping: func(context.Context) error { return nil }}},
+		{name: "without a check", inner: &stubProvider{name: "static"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAdaptiveProvider(tt.inner, 1, 10)
+			if err := p.Ping(context.Background()); !errors.Is(err, tt.want) {
+				t.Errorf("Ping = %v, want %v", err, tt.want)
+			}
+			if p.Name() != tt.inner.Name() || p.Unwrap() != tt.inner {
+				t.Errorf("decorator reports %q wrapping %v, want %q", p.Name(), p.Unwrap(), tt.inner.Name())
+			}
+		})
+	}
+}
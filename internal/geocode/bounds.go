@@ -0,0 +1,39 @@
+package geocode
+
+// Point is a latitude/longitude pair.
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Bounds is the smallest latitude/longitude box containing every extended point. It does not
+// account for boxes crossing the antimeridian.
+type Bounds struct {
+	SouthWest Point `json:"southwest"`
+	NorthEast Point `json:"northeast"`
+	empty     bool
+}
+
+// NewBounds returns an empty box.
+func NewBounds() Bounds {
+	return Bounds{empty: true}
+}
+
+// Extend grows the box to include the given coordinate.
+func (b *Bounds) Extend(lat, lng float64) {
+	if b.empty {
+		b.SouthWest = Point{Latitude: lat, Longitude: lng}
+		b.NorthEast = b.SouthWest
+		b.empty = false
+		return
+	}
+	b.SouthWest.Latitude = min(b.SouthWest.Latitude, lat)
+	b.SouthWest.Longitude = min(b.SouthWest.Longitude, lng)
+	b.NorthEast.Latitude = max(b.NorthEast.Latitude, lat)
+	b.NorthEast.Longitude = max(b.NorthEast.Longitude, lng)
+}
+
+// Empty reports whether no point has been added.
+func (b Bounds) Empty() bool {
+	return b.empty
+}
@@ -0,0 +1,50 @@
+package geocode
+
+import "testing"
+
+func TestBoundsExtend(t *testing.T) {
+	tests := []struct {
+		name                  string
+		points                []Point
+		wantSouthWest, wantNE Point
+	}{
+		{
+			name:          "single point",
+			points:        []Point{{-23.5505, -46.6333}},
+			wantSouthWest: Point{-23.5505, -46.6333},
+			wantNE:        Point{-23.5505, -46.6333},
+		},
+		{
+			name:          "São Paulo, Rio de Janeiro and Brasília",
+			points:        []Point{{-23.5505, -46.6333}, {-22.9068, -43.1729}, {-15.7939, -47.8828}},
+			wantSouthWest: Point{-23.5505, -47.8828},
+			wantNE:        Point{-15.7939, -43.1729},
+		},
+		{
+			name:          "across the equator and the prime meridian",
+			points:        []Point{{51.5072, -0.1276}, {-33.9249, 18.4241}, {6.5244, 3.3792}},
+			wantSouthWest: Point{-33.9249, -0.1276},
+			wantNE:        Point{51.5072, 18.4241},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBounds()
+			for _, p := range tt.points {
+				b.Extend(p.Latitude, p.Longitude)
+			}
+			if b.Empty() {
+				t.Fatal("bounds are empty after extending them")
+			}
+			if b.SouthWest != tt.wantSouthWest || b.NorthEast != tt.wantNE {
+				t.Errorf("bounds = %v to %v, want %v to %v", b.SouthWest, b.NorthEast, tt.wantSouthWest, tt.wantNE)
+			}
+		})
+	}
+}
+
+func TestNewBoundsIsEmpty(t *testing.T) {
+	if !NewBounds().Empty() {
+		t.Fatal("NewBounds is not empty")
+	}
+}
@@ -0,0 +1,241 @@
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheExpiresWithTheInjectedClock(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		wantHit bool
+	}{
+		{name: "fresh", advance: 0, wantHit: true},
+		{name: "just before the TTL", advance: time.Minute - time.Second, wantHit: true},
+		{name: "at the TTL", advance: time.Minute, wantHit: true},
+		{name: "past the TTL", advance: time.Minute + time.Nanosecond},
+		{name: "long past the TTL", advance: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newCache(time.Minute, clock.Now, 4)
+			c.Set("praça da sé", Result{Latitude: -23.55, Longitude: -46.63})
+
+			clock.Advance(tt.advance)
+			if _, hit := c.Get("praça da sé"); hit != tt.wantHit {
+				t.Fatalf("Get after %s: hit = %v, want %v", tt.advance, hit, tt.wantHit)
+			}
+			// An expired entry is dropped on read rather than left behind.
+			if c.contains("praça da sé") != tt.wantHit {
+				t.Errorf("entry kept = %v, want %v", !tt.wantHit, tt.wantHit)
+			}
+		})
+	}
+}
+
+func TestGeocodeRefetchesAfterTheTTL(t *testing.T) {
+	clock := newFakeClock()
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "stub"})}
+	s := newTestService(t, WithClock(clock.Now), WithCacheTTL(time.Hour), WithProviders(provider))
+
+	steps := []struct {
+		advance    time.Duration
+		wantSource string
+		wantCalls  int32
+	}{
+		{wantSource: "stub", wantCalls: 1},
+		{advance: 59 * time.Minute, wantSource: "cache", wantCalls: 1},
+		{advance: 2 * time.Minute, wantSource: "stub", wantCalls: 2},
+		{advance: 30 * time.Minute, wantSource: "cache", wantCalls: 2},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		result, err := s.Geocode(context.Background(), "Praça da Sé")
+		if err != nil {
+			t.Fatalf("step %d: Geocode: %v", i, err)
+		}
+		if result.Source != step.wantSource || provider.calls.Load() != step.wantCalls {
+			t.Errorf("step %d: source %s after %d calls, want %s after %d", i, result.Source, provider.calls.Load(), step.wantSource, step.wantCalls)
+		}
+	}
+}
+
+func TestCacheHitKeepsRetrievalTime(t *testing.T) {
+	clock := newFakeClock()
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "stub"})}
+	s := newTestService(t, WithClock(clock.Now), WithCacheTTL(time.Hour), WithProviders(provider))
+	retrieved := clock.Now()
+
+	for _, advance := range []time.Duration{0, 10 * time.Minute, 40 * time.Minute} {
+		clock.Advance(advance)
+		result, err := s.Geocode(context.Background(), "Praça da Sé")
+		if err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+		if !result.RetrievedAt.Equal(retrieved) || !result.ExpiresAt.Equal(retrieved.Add(time.Hour)) {
+			t.Errorf("at %s: retrieved %s, expires %s; want %s and an hour later", clock.Now(), result.RetrievedAt, result.ExpiresAt, retrieved)
+		}
+	}
+	if provider.calls.Load() != 1 {
+		t.Fatalf("provider calls = %d, want 1", provider.calls.Load())
+	}
+}
+
+func TestPrecisionTTLs(t *testing.T) {
+	tests := []struct {
+		precision string
+		wantTTL   time.Duration
+	}{
+		{precision: "ROOFTOP", wantTTL: 24 * time.Hour},
+		{precision: "APPROXIMATE", wantTTL: 10 * time.Minute},
+		{precision: "GEOMETRIC_CENTER", wantTTL: time.Hour},
+		{precision: "", wantTTL: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.precision, func(t *testing.T) {
+			clock := newFakeClock()
+			provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "stub", Precision: tt.precision})}
+			s := newTestService(t, WithClock(clock.Now), WithCacheTTL(time.Hour), WithProviders(provider),
+				WithPrecisionTTLs(map[string]time.Duration{"ROOFTOP": 24 * time.Hour, "APPROXIMATE": 10 * time.Minute}))
+
+			result, err := s.Geocode(context.Background(), "Praça da Sé")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if got := result.ExpiresAt.Sub(result.RetrievedAt); got != tt.wantTTL {
+				t.Errorf("stored for %s, want %s", got, tt.wantTTL)
+			}
+
+			for _, step := range []struct {
+				advance   time.Duration
+				wantCalls int32
+			}{{advance: tt.wantTTL, wantCalls: 1}, {advance: time.Second, wantCalls: 2}} {
+				clock.Advance(step.advance)
+				if _, err := s.Geocode(context.Background(), "Praça da Sé"); err != nil {
+					t.Fatalf("Geocode: %v", err)
+				}
+				if got := provider.calls.Load(); got != step.wantCalls {
+					t.Errorf("provider calls after %s = %d, want %d", step.advance, got, step.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPrecisionTTLsRejectsInvalidEntries(t *testing.T) {
+	for name, ttls := range map[string]map[string]time.Duration{
+		"unknown precision": {"EXACT": time.Hour},
+		"zero ttl":          {"ROOFTOP": 0},
+		"negative ttl":      {"APPROXIMATE": -time.Minute},
+	} {
+		if _, err := New(WithPrecisionTTLs(ttls)); err == nil {
+			t.Errorf("%s: New accepted %v", name, ttls)
+		}
+	}
+}
+
+func TestCacheLenAcrossShards(t *testing.T) {
+	for _, shards := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("%d shards", shards), func(t *testing.T) {
+			c := newCache(time.Minute, time.Now, shards)
+			for i := 0; i < 100; i++ {
+				c.Set(fmt.Sprintf("rua %d", i), Result{Latitude: float64(i)})
+			}
+			c.Set("rua 0", Result{Latitude: -1})
+			if got := c.Len(); got != 100 {
+				t.Errorf("Len = %d, want 100", got)
+			}
+			if got, ok := c.Get("rua 0"); !ok || got.Latitude != -1 {
+				t.Errorf("Get(rua 0) = %v, %v, want the overwritten entry", got.Latitude, ok)
+			}
+		})
+	}
+}
+
+func BenchmarkCacheParallel(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("rua %d, são paulo", i)
+	}
+	for _, shards := range []int{1, 16} {
+		b.Run(fmt.Sprintf("%d shards", shards), func(b *testing.B) {
+			c := newCache(time.Hour, time.Now, shards)
+			for _, key := range keys {
+				c.Set(key, Result{Latitude: -23.55, Longitude: -46.63})
+			}
+			var worker atomic.Int32
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// Each goroutine walks the keys from its own offset, writing one in every eight.
+				i := int(worker.Add(1)) * 97
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if i%8 == 0 {
+						c.Set(key, Result{Latitude: -23.55, Longitude: -46.63})
+					} else {
+						c.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestProviderTTLs(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    string
+		wantSource string
+		wantTTL    time.Duration
+	}{
+		{name: "nominatim result", address: "Rua Direita", wantSource: "nominatim", wantTTL: 7 * 24 * time.Hour},
+		{name: "google fallback result", address: "Praça da Sé", wantSource: "google", wantTTL: time.Hour},
+		{name: "provider without a TTL", address: "Avenida Paulista", wantSource: "static", wantTTL: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+			nominatim := &stubProvider{name: "nominatim", answer: func(_ context.Context, address string) (Result, error) {
+				if address != "rua direita" {
+					return Result{}, ErrNoResults
+				}
+				return Result{Address: address, Latitude: -23.54, Longitude: -46.63, Source: "nominatim"}, nil
+			}}
+			static := NewStaticProvider([]StaticEntry{{Address: "Avenida Paulista", Latitude: -23.56, Longitude: -46.65}})
+			s := newTestService(t, google.option(), WithClock(clock.Now), WithCacheTTL(30*time.Minute),
+				WithProviders(static, nominatim),
+				// The provider TTL takes precedence over the precision TTL of Google's GEOMETRIC_CENTER result.
+				WithPrecisionTTLs(map[string]time.Duration{PrecisionGeometricCenter: 5 * time.Minute}),
+				WithProviderTTLs(map[string]time.Duration{"nominatim": 7 * 24 * time.Hour, "google": time.Hour}))
+
+			result, err := s.Geocode(context.Background(), tt.address)
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Source != tt.wantSource {
+				t.Fatalf("source = %s, want %s", result.Source, tt.wantSource)
+			}
+			if got := result.ExpiresAt.Sub(result.RetrievedAt); got != tt.wantTTL {
+				t.Errorf("stored for %s, want %s", got, tt.wantTTL)
+			}
+
+			clock.Advance(tt.wantTTL)
+			if cached, _ := s.Geocode(context.Background(), tt.address); cached.Source != "cache" {
+				t.Errorf("source at the TTL = %s, want cache", cached.Source)
+			}
+			clock.Advance(time.Second)
+			if refetched, _ := s.Geocode(context.Background(), tt.address); refetched.Source != tt.wantSource {
+				t.Errorf("source past the TTL = %s, want %s", refetched.Source, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package geocode
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxCanonicalInputBytes bounds the raw inputs remembered by the canonicalization cache, so a few
+// huge inputs cannot take up most of its memory.
+const maxCanonicalInputBytes = 256
+
+// canonicalForm is the outcome of preprocessing a raw address.
+type canonicalForm struct {
+	// address is the normalized address, and key the cache key derived from it.
+	address string
+	key     string
+	// coordinates is set when address is a "lat,lng" pair.
+	coordinates bool
+}
+
+// canonicalCache remembers the canonical form of recent raw inputs so recurring ones skip the
+// coordinate check and default-country handling. It holds at most size entries; once full, an
+// arbitrary entry makes room for each new one.
+type canonicalCache struct {
+	size int
+
+	mu    sync.RWMutex
+	items map[string]canonicalForm
+}
+
+func newCanonicalCache(size int) *canonicalCache {
+	if size <= 0 {
+		return nil
+	}
+	return &canonicalCache{size: size, items: make(map[string]canonicalForm, size)}
+}
+
+// canonicalize returns the canonical form of rawAddress, from the canonicalization cache when
+// possible.
+func (s *Service) canonicalize(rawAddress string) canonicalForm {
+	c := s.canonical
+	if c == nil || len(rawAddress) > maxCanonicalInputBytes {
+		return s.preprocess(rawAddress)
+	}
+
+	c.mu.RLock()
+	form, ok := c.items[rawAddress]
+	c.mu.RUnlock()
+	if ok {
+		return form
+	}
+
+	form = s.preprocess(rawAddress)
+	c.mu.Lock()
+	if len(c.items) >= c.size {
+		for evict := range c.items {
+			delete(c.items, evict)
+			break
+		}
+	}
+	c.items[rawAddress] = form
+	c.mu.Unlock()
+	return form
+}
+
+// preprocess normalizes rawAddress and derives its cache key, applying the default country to
+// anything but coordinates.
+func (s *Service) preprocess(rawAddress string) canonicalForm {
+	address := normalizeAddress(rawAddress)
+	if address == "" {
+		return canonicalForm{}
+	}
+	if _, _, ok := ParseCoordinates(address); ok {
+		return canonicalForm{address: address, key: address, coordinates: true}
+	}
+	return canonicalForm{address: address, key: s.defaultCountry.apply(address)}
+}
+
+// WithCanonicalCache remembers the canonical form of up to size recent raw addresses, so recurring
+// inputs skip preprocessing. Zero disables it.
+func WithCanonicalCache(size int) Option {
+	return func(o *options) error {
+		if size < 0 {
+			return fmt.Errorf("canonical cache size must not be negative, got %d", size)
+		}
+		o.canonicalCacheSize = size
+		return nil
+	}
+}
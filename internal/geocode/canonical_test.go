@@ -0,0 +1,97 @@
+package geocode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalCacheMatchesPreprocessing(t *testing.T) {
+	inputs := []string{
+		"Praça da Sé, São Paulo",
+		"  PRAÇA   DA SÉ,  são paulo ",
+		"Avenida Paulista, 1000, Brasil",
+		"-23.5505,-46.6333",
+		" 40.7128 , -74.0060 ",
+		"Rua 7, 15",
+		"   ",
+		"",
+		strings.Repeat("rua muito longa ", 20),
+	}
+	uncached := newTestService(t, WithDefaultCountry("Brasil", "Brazil"))
+	cached := newTestService(t, WithDefaultCountry("Brasil", "Brazil"), WithCanonicalCache(16))
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			want := uncached.canonicalize(input)
+			// The second call is answered by the canonicalization cache.
+			for i := 0; i < 2; i++ {
+				if got := cached.canonicalize(input); got != want {
+					t.Errorf("call %d: canonicalize(%q) = %+v, want %+v", i+1, input, got, want)
+				}
+			}
+		})
+	}
+	if _, ok := cached.canonical.items[inputs[len(inputs)-1]]; ok {
+		t.Error("an input over maxCanonicalInputBytes was cached")
+	}
+}
+
+func TestCanonicalCacheSizeCap(t *testing.T) {
+	tests := []struct {
+		size, inputs int
+		want         int
+	}{
+		{size: 4, inputs: 3, want: 3},
+		{size: 4, inputs: 4, want: 4},
+		{size: 4, inputs: 100, want: 4},
+		{size: 1, inputs: 10, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d inputs in %d", tt.inputs, tt.size), func(t *testing.T) {
+			s := newTestService(t, WithCanonicalCache(tt.size))
+			for i := 0; i < tt.inputs; i++ {
+				s.canonicalize(fmt.Sprintf("Rua %d, São Paulo", i))
+			}
+			if got := len(s.canonical.items); got != tt.want {
+				t.Errorf("entries = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCanonicalCacheDisabled(t *testing.T) {
+	s := newTestService(t, WithCanonicalCache(0))
+	if s.canonical != nil {
+		t.Fatal("canonicalization cache created with size 0")
+	}
+	if _, err := New(WithCanonicalCache(-1)); err == nil {
+		t.Error("New accepted a negative size")
+	}
+}
+
+func BenchmarkCanonicalize(b *testing.B) {
+	inputs := []string{
+		"  Praça da Sé, São Paulo  ",
+		"AVENIDA PAULISTA, 1000",
+		"Rua Augusta,   1500, Consolação",
+		"-23.5505, -46.6333",
+	}
+	for _, size := range []int{0, 1024} {
+		name := "without cache"
+		if size > 0 {
+			name = "with cache"
+		}
+		b.Run(name, func(b *testing.B) {
+			s, err := New(WithDefaultCountry("Brasil", "Brazil"), WithCanonicalCache(size))
+			if err != nil {
+				b.Fatalf("New: %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.canonicalize(inputs[i%len(inputs)])
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrChaosInjected is returned by a ChaosProvider for the calls it deliberately fails.
+var ErrChaosInjected = errors.New("failure injected by chaos testing")
+
+// ChaosProvider decorates a provider to fail a fraction of its calls and delay every call, so
+// retry and fallback behavior can be exercised in test and staging environments. It must never be
+// installed in production.
+type ChaosProvider struct {
+	inner       Provider
+	failureRate float64
+	latency     time.Duration
+}
+
+// NewChaosProvider wraps inner so that each call is delayed by latency and then fails with
+// probability failureRate, between 0 and 1.
+func NewChaosProvider(inner Provider, failureRate float64, latency time.Duration) *ChaosProvider {
+	return &ChaosProvider{inner: inner, failureRate: failureRate, latency: latency}
+}
+
+// Name reports the name of the decorated provider.
+func (p *ChaosProvider) Name() string {
+	return p.inner.Name()
+}
+
+// Unwrap returns the decorated provider.
+func (p *ChaosProvider) Unwrap() Provider {
+	return p.inner
+}
+
+// Ping forwards to the decorated provider's readiness check. Providers without one are always
+// reachable.
+func (p *ChaosProvider) Ping(ctx context.Context) error {
+	if pinger, ok := pingerOf(p.inner); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Geocode delays, possibly fails, and otherwise delegates to the decorated provider.
+func (p *ChaosProvider) Geocode(ctx context.Context, address string) (Result, error) {
+	if err := p.inject(ctx); err != nil {
+		return Result{}, err
+	}
+	return p.inner.Geocode(ctx, address)
+}
+
+// GeocodeFiltered delays, possibly fails, and otherwise delegates to the decorated provider, which
+// answers ErrNoResults if it cannot apply filters.
+func (p *ChaosProvider) GeocodeFiltered(ctx context.Context, address, components string) (Result, error) {
+	if err := p.inject(ctx); err != nil {
+		return Result{}, err
+	}
+	return query{address: address, components: components}.geocode(ctx, p.inner)
+}
+
+// TimeZone delays, possibly fails, and otherwise delegates to the decorated provider, which answers
+// ErrNoResults if it has no time zones.
+func (p *ChaosProvider) TimeZone(ctx context.Context, lat, lng float64, at time.Time) (TimeZone, error) {
+	if err := p.inject(ctx); err != nil {
+		return TimeZone{}, err
+	}
+	return timeZoneOf(ctx, p.inner, lat, lng, at)
+}
+
+// inject applies the configured latency and then fails with the configured probability.
+func (p *ChaosProvider) inject(ctx context.Context) error {
+	if p.latency > 0 {
+		timer := time.NewTimer(p.latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if p.failureRate > 0 && rand.Float64() < p.failureRate {
+		return ErrChaosInjected
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChaosProviderFailureRate(t *testing.T) {
+	const calls = 200
+	tests := []struct {
+		name         string
+		failureRate  float64
+		wantFailures int
+	}{
+		{name: "never", failureRate: 0, wantFailures: 0},
+		{name: "always", failureRate: 1, wantFailures: calls},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &stubProvider{name: "inner", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63})}
+			chaos := NewChaosProvider(inner, tt.failureRate, 0)
+
+			failures := 0
+			for i := 0; i < calls; i++ {
+				_, err := chaos.Geocode(context.Background(), "Praça da Sé")
+				switch {
+				case errors.Is(err, ErrChaosInjected):
+					failures++
+				case err != nil:
+					t.Fatalf("Geocode = %v, want nil or ErrChaosInjected", err)
+				}
+			}
+			if failures != tt.wantFailures {
+				t.Errorf("failures = %d, want %d", failures, tt.wantFailures)
+			}
+			if got := int(inner.calls.Load()); got != calls-tt.wantFailures {
+				t.Errorf("inner calls = %d, want %d", got, calls-tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestChaosProviderLatencyHonorsTheContext(t *testing.T) {
+	inner := &stubProvider{name: "inner", answer: answerWith(Result{})}
+	chaos := NewChaosProvider(inner, 0, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := chaos.Geocode(ctx, "Praça da Sé"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Geocode = %v, want context.DeadlineExceeded", err)
+	}
+	if inner.calls.Load() != 0 {
+		t.Fatal("the decorated provider was called after the deadline")
+	}
+}
+
+func TestChaosDecoratorFailsTheWholeChain(t *testing.T) {
+	inner := &stubProvider{name: "inner", answer: answerWith(Result{})}
+	s := newTestService(t, WithProviders(inner), WithFailureTTLs(0, 0), WithProviderDecorator(func(p Provider) Provider {
+		return NewChaosProvider(p, 1, 0)
+	}))
+
+	if _, err := s.Geocode(context.Background(), "Praça da Sé"); !errors.Is(err, ErrChaosInjected) {
+		t.Fatalf("Geocode = %v, want ErrChaosInjected", err)
+	}
+	if inner.calls.Load() != 0 {
+		t.Fatal("the decorated provider was called at a 100% failure rate")
+	}
+}
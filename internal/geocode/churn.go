@@ -0,0 +1,82 @@
+package geocode
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultChurnWindow is the window over which new cache keys are counted when none is configured.
+const DefaultChurnWindow = time.Minute
+
+// churnGuard counts new cache keys per fixed window. Once more than limit new keys arrive within a
+// window, new entries stop being cached until the next window starts, so a client sending endless
+// unique addresses cannot grow the cache without bound. Existing entries are still refreshed.
+type churnGuard struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+	notify func(paused bool, newKeys int)
+
+	mu      sync.Mutex
+	start   time.Time
+	newKeys int
+	paused  bool
+}
+
+func newChurnGuard(limit int, window time.Duration, now func() time.Time, notify func(bool, int)) *churnGuard {
+	if window <= 0 {
+		window = DefaultChurnWindow
+	}
+	return &churnGuard{limit: limit, window: window, now: now, notify: notify}
+}
+
+// admit records a new key and reports whether it may be cached.
+func (g *churnGuard) admit() bool {
+	now := g.now()
+
+	g.mu.Lock()
+	resumed, previous := false, 0
+	if now.Sub(g.start) >= g.window {
+		resumed, previous = g.paused, g.newKeys
+		g.start, g.newKeys, g.paused = now, 0, false
+	}
+	g.newKeys++
+	paused := g.limit > 0 && g.newKeys > g.limit
+	tripped := paused && !g.paused
+	g.paused = paused
+	newKeys := g.newKeys
+	g.mu.Unlock()
+
+	if resumed {
+		g.notify(false, previous)
+	}
+	if tripped {
+		g.notify(true, newKeys)
+	}
+	return !paused
+}
+
+// isPaused reports whether caching of new entries is currently paused.
+func (g *churnGuard) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused && g.now().Sub(g.start) < g.window
+}
+
+// WithChurnLimit stops caching new entries for the rest of the window once more than limit new
+// keys were cached within window. notify, which may be nil, is called when caching pauses and when
+// it resumes, with the number of new keys seen in the window. Zero disables the limit.
+func WithChurnLimit(limit int, window time.Duration, notify func(paused bool, newKeys int)) Option {
+	return func(o *options) error {
+		if limit < 0 || window < 0 {
+			return fmt.Errorf("churn limit and window must not be negative")
+		}
+		o.churnLimit = limit
+		o.churnWindow = window
+		if notify != nil {
+			o.churnNotify = notify
+		}
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSink totals the counts reported for each metric name, and for each name with its tags
+// as "name|tag,tag".
+type countingSink struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (s *countingSink) Count(name string, value int64, tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[string]int64)
+	}
+	s.counts[name] += value
+	if len(tags) > 0 {
+		s.counts[name+"|"+strings.Join(tags, ",")] += value
+	}
+}
+
+func (s *countingSink) Timing(string, time.Duration, ...string) {}
+
+func (s *countingSink) get(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[name]
+}
+
+func TestChurnLimitPausesNewEntries(t *testing.T) {
+	clock := newFakeClock()
+	sink := &countingSink{}
+	var notified []string
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "stub"})}
+	s := newTestService(t, WithClock(clock.Now), WithMetrics(sink), WithProviders(provider),
+		WithChurnLimit(3, time.Minute, func(paused bool, newKeys int) {
+			if paused {
+				notified = append(notified, "paused")
+			} else {
+				notified = append(notified, "resumed")
+			}
+		}))
+
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		address     string
+		wantSource  string
+		wantPaused  bool
+		wantNewKeys int64
+		wantSkipped int64
+	}{
+		{name: "first new key", address: "Rua 1", wantSource: "stub", wantNewKeys: 1},
+		{name: "second new key", address: "Rua 2", wantSource: "stub", wantNewKeys: 2},
+		{name: "third new key", address: "Rua 3", wantSource: "stub", wantNewKeys: 3},
+		{name: "over the limit", address: "Rua 4", wantSource: "stub", wantPaused: true, wantNewKeys: 4, wantSkipped: 1},
+		{name: "skipped key is not cached", address: "Rua 4", wantSource: "stub", wantPaused: true, wantNewKeys: 5, wantSkipped: 2},
+		{name: "existing entries still hit", address: "Rua 1", wantSource: "cache", wantPaused: true, wantNewKeys: 5, wantSkipped: 2},
+		{name: "next window caches again", advance: time.Minute, address: "Rua 4", wantSource: "stub", wantNewKeys: 6, wantSkipped: 2},
+		{name: "cached in the new window", address: "Rua 4", wantSource: "cache", wantNewKeys: 6, wantSkipped: 2},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		result, err := s.Geocode(context.Background(), step.address)
+		if err != nil {
+			t.Fatalf("%s: Geocode: %v", step.name, err)
+		}
+		if result.Source != step.wantSource {
+			t.Errorf("%s: source = %s, want %s", step.name, result.Source, step.wantSource)
+		}
+		if got := s.CacheStats().CachingPaused; got != step.wantPaused {
+			t.Errorf("%s: caching paused = %v, want %v", step.name, got, step.wantPaused)
+		}
+		if got := sink.get("cache.new_key"); got != step.wantNewKeys {
+			t.Errorf("%s: cache.new_key = %d, want %d", step.name, got, step.wantNewKeys)
+		}
+		if got := sink.get("cache.skipped"); got != step.wantSkipped {
+			t.Errorf("%s: cache.skipped = %d, want %d", step.name, got, step.wantSkipped)
+		}
+	}
+	if want := []string{"paused", "resumed"}; !reflect.DeepEqual(notified, want) {
+		t.Errorf("notifications = %q, want %q", notified, want)
+	}
+}
+
+func TestChurnLimitDisabled(t *testing.T) {
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "stub"})}
+	s := newTestService(t, WithProviders(provider), WithChurnLimit(0, time.Minute, nil))
+	for i := 0; i < 50; i++ {
+		if _, err := s.Geocode(context.Background(), fmt.Sprintf("Rua %d", i)); err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+	}
+	if stats := s.CacheStats(); stats.CachingPaused || stats.Entries != 50 {
+		t.Errorf("paused %v with %d entries, want every entry cached", stats.CachingPaused, stats.Entries)
+	}
+}
@@ -0,0 +1,48 @@
+package geocode
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// ErrCoordinatesInput is returned when a coordinate pair is submitted as an address while the
+// service is configured to reject such input.
+var ErrCoordinatesInput = errors.New("address looks like a latitude,longitude pair; geocoding expects a street address")
+
+// Modes controlling how coordinate-like addresses are handled.
+const (
+	// CoordinateInputAllow forwards coordinate-like input to the provider unchanged.
+	CoordinateInputAllow = "allow"
+	// CoordinateInputReject fails coordinate-like input with ErrCoordinatesInput.
+	CoordinateInputReject = "reject"
+	// CoordinateInputFallback forwards coordinate-like input to the provider and, when the lookup
+	// fails, answers with the parsed coordinates instead. It keeps such input working while every
+	// provider is down.
+	CoordinateInputFallback = "fallback"
+)
+
+// SourceParsed marks results built from coordinates parsed out of the input.
+const SourceParsed = "parsed"
+
+// coordinatePattern matches exactly two comma-separated decimal numbers. Both numbers must have a
+// fractional part so that addresses such as "10, 200" or "Rua 7, 15" are never mistaken for
+// coordinates.
+var coordinatePattern = regexp.MustCompile(`^\s*([-+]?\d{1,3}\.\d+)\s*,\s*([-+]?\d{1,3}\.\d+)\s*$`)
+
+// ParseCoordinates reports whether input is a strict "lat,lng" pair within valid ranges.
+func ParseCoordinates(input string) (lat, lng float64, ok bool) {
+	match := coordinatePattern.FindStringSubmatch(input)
+	if match == nil {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lng, err = strconv.ParseFloat(match[2], 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
@@ -0,0 +1,105 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		input    string
+		ok       bool
+		lat, lng float64
+	}{
+		{input: "-23.5505,-46.6333", ok: true, lat: -23.5505, lng: -46.6333},
+		{input: " 40.7128 , -74.0060 ", ok: true, lat: 40.7128, lng: -74.006},
+		{input: "+1.5,+2.5", ok: true, lat: 1.5, lng: 2.5},
+		{input: "10, 200"},
+		{input: "Rua 7, 15"},
+		{input: "1000 5th Ave"},
+		{input: "91.0,10.0"},
+		{input: "10.0,181.0"},
+		{input: "-23.5505,-46.6333,10.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lat, lng, ok := ParseCoordinates(tt.input)
+			if ok != tt.ok || lat != tt.lat || lng != tt.lng {
+				t.Errorf("ParseCoordinates(%q) = %v, %v, %v; want %v, %v, %v", tt.input, lat, lng, ok, tt.lat, tt.lng, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGeocodeCoordinateInputModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		address   string
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "allow forwards coordinates", mode: CoordinateInputAllow, address: "-23.5505,-46.6333", wantCalls: 1},
+		{name: "reject refuses coordinates", mode: CoordinateInputReject, address: "-23.5505,-46.6333", wantErr: ErrCoordinatesInput},
+		{name: "reject keeps numeric addresses", mode: CoordinateInputReject, address: "Rua 7, 15", wantCalls: 1},
+		{name: "allow keeps numeric addresses", mode: CoordinateInputAllow, address: "Rua 7, 15", wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: 1, Longitude: 2})}
+			s := newTestService(t, WithCoordinateInputMode(tt.mode), WithProviders(provider))
+
+			_, err := s.Geocode(context.Background(), tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Geocode(%q) = %v, want %v", tt.address, err, tt.wantErr)
+			}
+			if got := provider.calls.Load(); got != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithCoordinateInputModeRejectsUnknownMode(t *testing.T) {
+	if _, err := New(WithCoordinateInputMode("maybe")); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestGeocodeCoordinateFallback(t *testing.T) {
+	failing := func(context.Context, string) (Result, error) { return Result{}, ErrNoResults }
+	tests := []struct {
+		name       string
+		address    string
+		answer     func(context.Context, string) (Result, error)
+		wantErr    bool
+		wantSource string
+		lat, lng   float64
+	}{
+		{name: "parsed when providers fail", address: "-23.5505,-46.6333", answer: failing, wantSource: SourceParsed, lat: -23.5505, lng: -46.6333},
+		{name: "provider answer wins", address: "-23.5505,-46.6333", answer: answerWith(Result{Latitude: 1, Longitude: 2, Source: "stub"}), wantSource: "stub", lat: 1, lng: 2},
+		{name: "latitude out of range", address: "91.0,10.0", answer: failing, wantErr: true},
+		{name: "longitude out of range", address: "10, 200", answer: failing, wantErr: true},
+		{name: "numeric address", address: "Rua 7, 15", answer: failing, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`))
+			provider := &stubProvider{name: "stub", answer: tt.answer}
+			s := newTestService(t, google.option(), WithCoordinateInputMode(CoordinateInputFallback), WithProviders(provider))
+
+			result, err := s.Geocode(context.Background(), tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Geocode(%q) error = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.Source != tt.wantSource || result.Latitude != tt.lat || result.Longitude != tt.lng {
+				t.Errorf("result = %s at %v, %v; want %s at %v, %v", result.Source, result.Latitude, result.Longitude, tt.wantSource, tt.lat, tt.lng)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package geocode
+
+import (
+	"strings"
+	"unicode"
+)
+
+// defaultCountry appends a country to addresses that do not appear to name one, so providers do
+// not have to guess the region.
+type defaultCountry struct {
+	suffix string
+	names  []string
+}
+
+// newDefaultCountry builds the augmentation from names, the first of which is appended. Every name
+// is recognized as already present. It returns nil when names is empty.
+func newDefaultCountry(names []string) *defaultCountry {
+	var cleaned []string
+	for _, name := range names {
+		if name = normalizeAddress(name); name != "" {
+			cleaned = append(cleaned, name)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil
+	}
+	return &defaultCountry{suffix: ", " + cleaned[0], names: cleaned}
+}
+
+// apply returns the normalized address with the default country appended, unless the address
+// already mentions a country. The check is deliberately conservative: an address is left alone
+// when one of the configured names appears as a whole word anywhere in it, or when its last
+// comma-separated part is a lone two or three letter word, which is read as a country code.
+func (c *defaultCountry) apply(address string) string {
+	if c == nil || c.mentionsCountry(address) {
+		return address
+	}
+	return address + c.suffix
+}
+
+func (c *defaultCountry) mentionsCountry(address string) bool {
+	padded := " " + strings.Join(addressWords(address), " ") + " "
+	for _, name := range c.names {
+		if words := addressWords(name); len(words) > 0 && strings.Contains(padded, " "+strings.Join(words, " ")+" ") {
+			return true
+		}
+	}
+
+	i := strings.LastIndexByte(address, ',')
+	if i < 0 {
+		return false
+	}
+	last := strings.TrimSpace(address[i+1:])
+	return len(last) >= 2 && len(last) <= 3 && isLetters(last)
+}
+
+// addressWords splits an address into its runs of letters and digits.
+func addressWords(address string) []string {
+	return strings.FieldsFunc(address, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestDefaultCountryApply(t *testing.T) {
+	c := newDefaultCountry([]string{" Brasil ", "Brazil", ""})
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "praça da sé, são paulo", want: "praça da sé, são paulo, brasil"},
+		{address: "avenida paulista, 1000", want: "avenida paulista, 1000, brasil"},
+		{address: "praça da sé, são paulo, brasil", want: "praça da sé, são paulo, brasil"},
+		{address: "praça da sé, são paulo, brazil", want: "praça da sé, são paulo, brazil"},
+		{address: "praça da sé - são paulo - brasil", want: "praça da sé - são paulo - brasil"},
+		{address: "rua do brasil, 100", want: "rua do brasil, 100"},
+		{address: "brasília, df", want: "brasília, df"},
+		{address: "brasília", want: "brasília, brasil"},
+		{address: "10 downing street, london, uk", want: "10 downing street, london, uk"},
+		{address: "1600 amphitheatre pkwy, usa", want: "1600 amphitheatre pkwy, usa"},
+		{address: "rua augusta, 1500", want: "rua augusta, 1500, brasil"},
+		{address: "rua augusta, sp1", want: "rua augusta, sp1, brasil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := c.apply(tt.address); got != tt.want {
+				t.Errorf("apply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var disabled *defaultCountry
+	if got := disabled.apply("praça da sé"); got != "praça da sé" {
+		t.Errorf("nil apply = %q, want the address unchanged", got)
+	}
+	if newDefaultCountry([]string{" ", ""}) != nil {
+		t.Error("blank names enabled the augmentation")
+	}
+}
+
+func TestDefaultCountryReachesTheUpstreamQuery(t *testing.T) {
+	var mu sync.Mutex
+	var queried []string
+	google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queried = append(queried, r.URL.Query().Get("address"))
+		mu.Unlock()
+		respond(http.StatusOK, sePayload)(w, r)
+	})
+	s := newTestService(t, google.option(), WithDefaultCountry("Brasil", "Brazil"))
+
+	for _, address := range []string{"Praça da Sé, São Paulo", "Praça da Sé, São Paulo, Brasil", "10 Downing Street, London, UK"} {
+		if _, err := s.Geocode(context.Background(), address); err != nil {
+			t.Fatalf("Geocode(%q): %v", address, err)
+		}
+	}
+	want := []string{"praça da sé, são paulo, brasil", "10 downing street, london, uk"}
+	if len(queried) != len(want) || queried[0] != want[0] || queried[1] != want[1] {
+		t.Errorf("queried %q, want %q", queried, want)
+	}
+}
+
+func TestDefaultCountryKeepsStaticEntriesMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "static.json")
+	dataset := `[{"address": "Praça da Sé, São Paulo", "latitude": -23.5503, "longitude": -46.6339}]`
+	if err := os.WriteFile(path, []byte(dataset), 0o600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+	static, err := LoadStaticProvider(path)
+	if err != nil {
+		t.Fatalf("LoadStaticProvider: %v", err)
+	}
+	fallback := &stubProvider{name: "fallback", answer: answerWith(Result{Source: "fallback"})}
+
+	for _, address := range []string{"Praça da Sé, São Paulo", "praça da sé, são paulo, brasil"} {
+		s := newTestService(t, WithProviders(static, fallback), WithDefaultCountry("Brasil"))
+		result, err := s.Geocode(context.Background(), address)
+		if err != nil {
+			t.Fatalf("Geocode(%q): %v", address, err)
+		}
+		if result.Source != "static" {
+			t.Errorf("Geocode(%q) answered by %s, want the static dataset", address, result.Source)
+		}
+	}
+	if fallback.calls.Load() != 0 {
+		t.Errorf("fallback calls = %d, want 0", fallback.calls.Load())
+	}
+}
@@ -0,0 +1,14 @@
+package geocode
+
+// DebugInfo carries the raw upstream response behind a result for troubleshooting.
+type DebugInfo struct {
+	Upstream  string `json:"upstream"`
+	Truncated bool   `json:"truncated"`
+}
+
+func newDebugInfo(raw []byte, maxBytes int) *DebugInfo {
+	if len(raw) <= maxBytes {
+		return &DebugInfo{Upstream: string(raw)}
+	}
+	return &DebugInfo{Upstream: string(raw[:maxBytes]), Truncated: true}
+}
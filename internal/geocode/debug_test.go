@@ -0,0 +1,20 @@
+package geocode
+
+import "testing"
+
+func TestNewDebugInfo(t *testing.T) {
+	tests := []struct {
+		raw      string
+		maxBytes int
+		want     DebugInfo
+	}{
+		{raw: `{"status":"OK"}`, maxBytes: 64, want: DebugInfo{Upstream: `{"status":"OK"}`}},
+		{raw: `{"status":"OK"}`, maxBytes: 15, want: DebugInfo{Upstream: `{"status":"OK"}`}},
+		{raw: `{"status":"OK"}`, maxBytes: 5, want: DebugInfo{Upstream: `{"sta`, Truncated: true}},
+	}
+	for _, tt := range tests {
+		if got := newDebugInfo([]byte(tt.raw), tt.maxBytes); *got != tt.want {
+			t.Errorf("newDebugInfo(%q, %d) = %+v, want %+v", tt.raw, tt.maxBytes, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package geocode
+
+import (
+	"context"
+	"fmt"
+)
+
+// ResultEnricher adjusts a freshly geocoded result before it is cached and returned. Enrichers
+// receive the request context so they can perform their own lookups within the request deadline.
+// Returning an error fails the lookup and nothing is cached.
+type ResultEnricher func(ctx context.Context, result *Result) error
+
+func (s *Service) enrich(ctx context.Context, result *Result) error {
+	for i, enricher := range s.enrichers {
+		if err := enricher(ctx, result); err != nil {
+			return fmt.Errorf("enricher %d: %w", i, err)
+		}
+	}
+	return nil
+}
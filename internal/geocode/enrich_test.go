@@ -0,0 +1,58 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEnrichersRunInOrderAndAreCached(t *testing.T) {
+	var order []string
+	tag := func(name string) ResultEnricher {
+		return func(_ context.Context, result *Result) error {
+			order = append(order, name)
+			if result.Extra == nil {
+				result.Extra = map[string]any{}
+			}
+			result.Extra["region"] = name
+			return nil
+		}
+	}
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: 1, Longitude: 2})}
+	s := newTestService(t, WithProviders(provider), WithEnrichers(tag("first"), tag("second")))
+
+	for i := 0; i < 2; i++ {
+		result, err := s.Geocode(context.Background(), "Avenida Paulista, 1000")
+		if err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+		if got := result.Extra["region"]; got != "second" {
+			t.Errorf("lookup %d: extra region = %v, want second", i, got)
+		}
+	}
+
+	cached, ok := s.cache.Get("avenida paulista, 1000")
+	if !ok || cached.Extra["region"] != "second" {
+		t.Fatalf("cached result = %+v, %v; want the enriched result", cached, ok)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("enrichers ran %v, want [first second] once", order)
+	}
+}
+
+func TestEnricherErrorFailsLookupWithoutCaching(t *testing.T) {
+	errEnrich := errors.New("enrichment failed")
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: 1, Longitude: 2})}
+	s := newTestService(t,
+		WithProviders(provider),
+		WithFailureTTLs(0, 0),
+		WithEnrichers(func(context.Context, *Result) error { return errEnrich }),
+	)
+
+	if _, err := s.Geocode(context.Background(), "Avenida Paulista, 1000"); !errors.Is(err, errEnrich) {
+		t.Fatalf("Geocode = %v, want the enricher error", err)
+	}
+	if _, ok := s.cache.Get("avenida paulista, 1000"); ok {
+		t.Fatal("a result that failed enrichment was cached")
+	}
+}
@@ -0,0 +1,69 @@
+package geocode
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// deniedStreak counts consecutive REQUEST_DENIED answers from Google, which usually mean the API
+// key was revoked or restricted. Once the streak reaches threshold, onFatal is called once. Google
+// answering normally resets the streak; network errors, timeouts, quota and 5xx errors neither
+// count nor reset it, so transient trouble can never trip it.
+type deniedStreak struct {
+	threshold int
+	onFatal   func(error)
+
+	mu    sync.Mutex
+	count int
+	fired bool
+}
+
+func newDeniedStreak(threshold int, onFatal func(error)) *deniedStreak {
+	if threshold <= 0 {
+		return nil
+	}
+	return &deniedStreak{threshold: threshold, onFatal: onFatal}
+}
+
+// record updates the streak with the outcome of a Google call. It is a no-op on a nil streak.
+func (d *deniedStreak) record(err error) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	switch {
+	case err == nil, errors.Is(err, ErrNoResults):
+		d.count = 0
+	case ErrorCategory(err) == CategoryDenied:
+		d.count++
+	}
+	trip := d.count >= d.threshold && !d.fired
+	if trip {
+		d.fired = true
+	}
+	count := d.count
+	d.mu.Unlock()
+
+	if trip {
+		d.onFatal(fmt.Errorf("%d consecutive denied Google requests: %w", count, err))
+	}
+}
+
+// WithFailFastOnDenied calls onFatal once Google has denied threshold consecutive requests, so the
+// process can shut down and let its orchestrator surface the failure instead of serving errors
+// indefinitely. onFatal runs on the request path and must not block. Zero disables the check.
+func WithFailFastOnDenied(threshold int, onFatal func(error)) Option {
+	return func(o *options) error {
+		if threshold < 0 {
+			return fmt.Errorf("denied threshold must not be negative, got %d", threshold)
+		}
+		if threshold > 0 && onFatal == nil {
+			return fmt.Errorf("fatal handler must not be nil")
+		}
+		o.deniedThreshold = threshold
+		o.onFatal = onFatal
+		return nil
+	}
+}
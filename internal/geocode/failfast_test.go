@@ -0,0 +1,76 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFailFastOnDenied(t *testing.T) {
+	const (
+		denied = `{"status": "REQUEST_DENIED", "results": [], "error_message": "The provided API key is invalid."}`
+		zero   = `{"status": "ZERO_RESULTS", "results": []}`
+		quota  = `{"status": "OVER_QUERY_LIMIT", "results": []}`
+	)
+	type answer struct {
+		status int
+		body   string
+	}
+	tests := []struct {
+		name      string
+		answers   []answer
+		wantFired int32
+	}{
+		{name: "consecutive denials", answers: []answer{{http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, denied}}, wantFired: 1},
+		{name: "fires once", answers: []answer{{http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, denied}}, wantFired: 1},
+		{name: "below the threshold", answers: []answer{{http.StatusOK, denied}, {http.StatusOK, denied}}},
+		{name: "success resets", answers: []answer{{http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, sePayload}, {http.StatusOK, denied}, {http.StatusOK, denied}}},
+		{name: "no results resets", answers: []answer{{http.StatusOK, denied}, {http.StatusOK, denied}, {http.StatusOK, zero}, {http.StatusOK, denied}, {http.StatusOK, denied}}},
+		{name: "transient errors neither count nor reset", answers: []answer{
+			{http.StatusOK, denied}, {http.StatusInternalServerError, ""}, {http.StatusTooManyRequests, ""}, {http.StatusOK, quota}, {http.StatusOK, denied}, {http.StatusServiceUnavailable, ""}, {http.StatusOK, denied},
+		}, wantFired: 1},
+		{name: "only transient errors", answers: []answer{{http.StatusInternalServerError, ""}, {http.StatusInternalServerError, ""}, {http.StatusTooManyRequests, ""}, {http.StatusOK, quota}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var call atomic.Int32
+			google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+				a := tt.answers[call.Add(1)-1]
+				respond(a.status, a.body)(w, r)
+			})
+			var fired atomic.Int32
+			var fatalErr error
+			s := newTestService(t, google.option(), WithFailureTTLs(0, 0), WithFailFastOnDenied(3, func(err error) {
+				fired.Add(1)
+				fatalErr = err
+			}))
+
+			for i := range tt.answers {
+				s.Geocode(context.Background(), fmt.Sprintf("Rua Direita, %d", i+100))
+			}
+			if got := fired.Load(); got != tt.wantFired {
+				t.Fatalf("onFatal calls = %d, want %d", got, tt.wantFired)
+			}
+			if tt.wantFired > 0 {
+				var apiErr *APIStatusError
+				if !errors.As(fatalErr, &apiErr) || apiErr.Status != "REQUEST_DENIED" {
+					t.Errorf("fatal error = %v, want the REQUEST_DENIED answer", fatalErr)
+				}
+			}
+		})
+	}
+}
+
+func TestWithFailFastOnDeniedRejectsInvalidSettings(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"negative threshold": WithFailFastOnDenied(-1, func(error) {}),
+		"nil handler":        WithFailFastOnDenied(3, nil),
+	} {
+		if _, err := New(opt); err == nil {
+			t.Errorf("%s: New accepted the option", name)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package geocode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrAddressBlocked is returned when an address is rejected by the configured filter.
+var ErrAddressBlocked = errors.New("address is not allowed")
+
+// regexRulePrefix marks a filter rule as a regular expression instead of a substring.
+const regexRulePrefix = "re:"
+
+// Filter rejects addresses matching a blocklist and, when an allowlist is configured, addresses
+// that match none of its rules. Rules are case-insensitive substrings, or regular expressions when
+// prefixed with "re:".
+type Filter struct {
+	block []rule
+	allow []rule
+}
+
+type rule struct {
+	substring string
+	pattern   *regexp.Regexp
+}
+
+func (r rule) matches(address string) bool {
+	if r.pattern != nil {
+		return r.pattern.MatchString(address)
+	}
+	return strings.Contains(address, r.substring)
+}
+
+// NewFilter compiles the provided blocklist and allowlist rules.
+func NewFilter(block, allow []string) (*Filter, error) {
+	blockRules, err := compileRules(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blocklist rule: %w", err)
+	}
+	allowRules, err := compileRules(allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowlist rule: %w", err)
+	}
+	return &Filter{block: blockRules, allow: allowRules}, nil
+}
+
+func compileRules(raw []string) ([]rule, error) {
+	rules := make([]rule, 0, len(raw))
+	for _, value := range raw {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(value, regexRulePrefix); ok {
+			pattern, err := regexp.Compile("(?i)" + expr)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule{pattern: pattern})
+			continue
+		}
+		rules = append(rules, rule{substring: normalizeAddress(value)})
+	}
+	return rules, nil
+}
+
+// Check returns ErrAddressBlocked when the normalized address is not allowed.
+func (f *Filter) Check(address string) error {
+	if f == nil {
+		return nil
+	}
+	for _, r := range f.block {
+		if r.matches(address) {
+			return ErrAddressBlocked
+		}
+	}
+	if len(f.allow) == 0 {
+		return nil
+	}
+	for _, r := range f.allow {
+		if r.matches(address) {
+			return nil
+		}
+	}
+	return ErrAddressBlocked
+}
+
+// SetFilter atomically replaces the address filter. Lookups already in progress keep using the
+// filter that was current when they started. A nil filter allows everything.
+func (s *Service) SetFilter(filter *Filter) {
+	s.filter.Store(filter)
+}
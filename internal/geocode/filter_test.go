@@ -0,0 +1,93 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFilterCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		block, allow []string
+		address      string
+		want         error
+	}{
+		{name: "no rules", address: "avenida paulista, 1000", want: nil},
+		{name: "blocked substring", block: []string{"Rua Proibida"}, address: "rua proibida, 10", want: ErrAddressBlocked},
+		{name: "not blocked", block: []string{"Rua Proibida"}, address: "rua permitida, 10", want: nil},
+		{name: "blocked pattern", block: []string{`re:^\d+$`}, address: "12345", want: ErrAddressBlocked},
+		{name: "allowed region", allow: []string{"SP", "re:rio de janeiro$"}, address: "avenida paulista, sp", want: nil},
+		{name: "allowed by pattern", allow: []string{"SP", "re:rio de janeiro$"}, address: "copacabana, rio de janeiro", want: nil},
+		{name: "outside the allowlist", allow: []string{"SP", "re:rio de janeiro$"}, address: "curitiba, pr", want: ErrAddressBlocked},
+		{name: "blocklist wins over allowlist", block: []string{"proibida"}, allow: []string{"sp"}, address: "rua proibida, sp", want: ErrAddressBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.block, tt.allow)
+			if err != nil {
+				t.Fatalf("NewFilter: %v", err)
+			}
+			if err := filter.Check(tt.address); !errors.Is(err, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.address, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	if _, err := NewFilter([]string{"re:("}, nil); err == nil {
+		t.Fatal("expected an error for an invalid regular expression")
+	}
+}
+
+func TestGeocodeBlockedAddressSkipsProviders(t *testing.T) {
+	filter, err := NewFilter([]string{"rua proibida"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: 1, Longitude: 2})}
+	s := newTestService(t, WithFilter(filter), WithProviders(provider))
+
+	if _, err := s.Geocode(context.Background(), "Rua Proibida, 10"); !errors.Is(err, ErrAddressBlocked) {
+		t.Fatalf("Geocode blocked address = %v, want ErrAddressBlocked", err)
+	}
+	if provider.calls.Load() != 0 {
+		t.Fatal("a blocked address reached the provider")
+	}
+	if _, err := s.Geocode(context.Background(), "Rua Permitida, 10"); err != nil {
+		t.Fatalf("Geocode allowed address: %v", err)
+	}
+	if provider.calls.Load() != 1 {
+		t.Fatalf("provider calls = %d, want 1", provider.calls.Load())
+	}
+}
+
+func TestSetFilterAppliesToSubsequentRequests(t *testing.T) {
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: 1, Longitude: 2})}
+	s := newTestService(t, WithProviders(provider))
+
+	steps := []struct {
+		block   []string
+		address string
+		blocked bool
+	}{
+		{address: "Rua Proibida, 10"},
+		{block: []string{"rua proibida"}, address: "Rua Proibida, 20", blocked: true},
+		{block: []string{"rua proibida"}, address: "Rua Permitida, 20"},
+		{block: []string{"rua permitida"}, address: "Rua Proibida, 30"},
+		{block: []string{"rua permitida"}, address: "Rua Permitida, 30", blocked: true},
+	}
+	for _, step := range steps {
+		filter, err := NewFilter(step.block, nil)
+		if err != nil {
+			t.Fatalf("NewFilter: %v", err)
+		}
+		s.SetFilter(filter)
+
+		_, err = s.Geocode(context.Background(), step.address)
+		if blocked := errors.Is(err, ErrAddressBlocked); blocked != step.blocked {
+			t.Errorf("block %v: Geocode(%q) = %v, want blocked %v", step.block, step.address, err, step.blocked)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default windows for remembering failed lookups.
+const (
+	DefaultFailureTTL   = 5 * time.Second
+	DefaultNoResultsTTL = time.Minute
+)
+
+// flightGroup deduplicates concurrent lookups for the same key so a burst of identical requests
+// results in a single upstream call.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{}
+	result Result
+	err    error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// Do runs fn once per key among concurrent callers. Callers joining an in-flight call stop waiting
+// when their own context is done, without affecting the shared call.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func() (Result, error)) (Result, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.result, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// failureCache briefly remembers failed lookups so that the wave of requests following a failure
+// does not immediately hit the upstream again. ErrNoResults is remembered for longer than other
+// failures since it is unlikely to change within minutes. Expired entries are dropped when read and
+// swept as the cache grows, so failures for addresses never requested again do not accumulate.
+type failureCache struct {
+	failureTTL   time.Duration
+	noResultsTTL time.Duration
+	now          func() time.Time
+
+	mu    sync.Mutex
+	items map[string]failureItem
+	sweep sweepSchedule
+}
+
+type failureItem struct {
+	err     error
+	expires time.Time
+}
+
+func newFailureCache(failureTTL, noResultsTTL time.Duration, now func() time.Time) *failureCache {
+	return &failureCache{
+		failureTTL:   failureTTL,
+		noResultsTTL: noResultsTTL,
+		now:          now,
+		items:        make(map[string]failureItem),
+	}
+}
+
+// Get returns the remembered failure for key, or nil when there is none.
+func (c *failureCache) Get(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if c.now().After(item.expires) {
+		delete(c.items, key)
+		return nil
+	}
+	return item.err
+}
+
+// Set records err for key. Context errors and overload describe the caller or the service rather
+// than the upstream, and the quota cap lifts on its own schedule, so none of them is remembered.
+func (c *failureCache) Set(key string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrOverloaded) || errors.Is(err, ErrQuotaCapReached) {
+		return
+	}
+
+	ttl := c.failureTTL
+	if errors.Is(err, ErrNoResults) {
+		ttl = c.noResultsTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sweep.due(len(c.items)) {
+		for k, item := range c.items {
+			if now.After(item.expires) {
+				delete(c.items, k)
+			}
+		}
+		c.sweep.swept(len(c.items))
+	}
+	c.items[key] = failureItem{err: err, expires: now.Add(ttl)}
+}
+
+// resolve returns the cached result for key or fetches it once across concurrent callers,
+// enriching and caching successful results and briefly remembering failures. query is the address
+// as the caller sent it; with the collision guard enabled, a cached result is only served when it
+// was looked up for a compatible query.
+func (s *Service) resolve(ctx context.Context, key, query string, fetch func(context.Context) (Result, error)) (Result, error) {
+	guarded := s.collisionGuard && query != ""
+	if result, ok := s.cache.Get(key); ok && (!guarded || compatibleQueries(result.Query, query)) {
+		s.counters.Load().record(true)
+		s.metrics.Count("cache.hit", 1)
+		TraceFromContext(ctx).answered(result.Source, true)
+		result.Source = "cache"
+		return result, nil
+	}
+	s.counters.Load().record(false)
+	s.metrics.Count("cache.miss", 1)
+
+	if err := s.failures.Get(key); err != nil {
+		return Result{}, err
+	}
+
+	flightKey := key
+	if guarded {
+		flightKey = key + "\x00" + queryFingerprint(query)
+	}
+	for {
+		result, err := s.flight.Do(ctx, flightKey, func() (Result, error) {
+			// Overload says nothing about the address, so it is not remembered as a failure.
+			if !s.inflight.tryAcquire() {
+				return Result{}, ErrOverloaded
+			}
+			defer s.inflight.release()
+
+			start := time.Now()
+			result, err := s.fetchWithCeiling(ctx, fetch)
+			recordUpstream(ctx, start)
+			if err == nil {
+				err = s.enrich(ctx, &result)
+			}
+			if err != nil {
+				s.failures.Set(key, err)
+				return Result{}, err
+			}
+			result.Query = query
+			result.RetrievedAt = s.memory.now()
+			result.ExpiresAt = result.RetrievedAt.Add(s.memory.ttlOf(result))
+			s.store(key, result)
+			return result, nil
+		})
+		// A shared call led by a request that was canceled must not fail the callers still waiting.
+		if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		if err == nil {
+			TraceFromContext(ctx).answered(result.Source, false)
+		}
+		return result, err
+	}
+}
+
+// store caches result under key. New keys are counted as "cache.new_key"; while the churn guard
+// has paused caching, they are counted as "cache.skipped" instead and not stored.
+func (s *Service) store(key string, result Result) {
+	if !s.memory.contains(key) {
+		s.metrics.Count("cache.new_key", 1)
+		if !s.churn.admit() {
+			s.metrics.Count("cache.skipped", 1)
+			return
+		}
+	}
+	s.cache.Set(key, result)
+}
+
+// DefaultMaxLookupDuration is the hard ceiling on a single upstream lookup when none is configured.
+const DefaultMaxLookupDuration = 10 * time.Second
+
+// ErrLookupTimeout is returned when a lookup exceeds the configured maximum duration, even though
+// the caller's own deadline has not been reached.
+var ErrLookupTimeout = errors.New("lookup exceeded the maximum allowed duration")
+
+// fetchWithCeiling runs fetch under the service's lookup ceiling so a caller with a very long or
+// missing deadline cannot pin a goroutine on a stuck upstream forever.
+func (s *Service) fetchWithCeiling(ctx context.Context, fetch func(context.Context) (Result, error)) (Result, error) {
+	if s.maxLookup <= 0 {
+		return fetch(ctx)
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.maxLookup)
+	defer cancel()
+
+	result, err := fetch(lookupCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(lookupCtx.Err(), context.DeadlineExceeded) {
+		return Result{}, ErrLookupTimeout
+	}
+	return result, err
+}
@@ -0,0 +1,159 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailingBurstIsNotRetriedWithinTheFailureWindow(t *testing.T) {
+	release := make(chan struct{})
+	google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		respond(http.StatusInternalServerError, "")(w, r)
+	})
+	clock := newFakeClock()
+	s := newTestService(t, google.option(), WithClock(clock.Now), WithFailureTTLs(10*time.Second, time.Minute))
+
+	burst := func(n int) []error {
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = s.Geocode(context.Background(), "Praça da Sé")
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// Let the first burst pile up behind its first, still unanswered, request.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	for i, err := range burst(20) {
+		var statusErr *UpstreamStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("first burst request %d = %v, want the upstream failure", i, err)
+		}
+	}
+	if got := google.requests.Load(); got != 1 {
+		t.Fatalf("first burst made %d upstream requests, want 1", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	for i, err := range burst(20) {
+		var statusErr *UpstreamStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("second burst request %d = %v, want the remembered failure", i, err)
+		}
+	}
+	if got := google.requests.Load(); got != 1 {
+		t.Fatalf("second burst within the window made %d more upstream requests, want 0", got-1)
+	}
+
+	clock.Advance(6 * time.Second)
+	if _, err := s.Geocode(context.Background(), "Praça da Sé"); err == nil {
+		t.Fatal("expected the retried lookup to fail again")
+	}
+	if got := google.requests.Load(); got != 2 {
+		t.Fatalf("a lookup after the window made %d upstream requests in total, want 2", got)
+	}
+}
+
+func TestFailureCacheWindows(t *testing.T) {
+	errUpstream := errors.New("upstream failed")
+	tests := []struct {
+		name       string
+		err        error
+		advance    time.Duration
+		remembered bool
+	}{
+		{name: "failure within its window", err: errUpstream, advance: 4 * time.Second, remembered: true},
+		{name: "failure after its window", err: errUpstream, advance: 6 * time.Second},
+		{name: "no results within its window", err: ErrNoResults, advance: 50 * time.Second, remembered: true},
+		{name: "no results after its window", err: ErrNoResults, advance: 61 * time.Second},
+		{name: "cancellation is never remembered", err: context.Canceled},
+		{name: "deadline is never remembered", err: context.DeadlineExceeded},
+		{name: "quota cap is never remembered", err: ErrQuotaCapReached},
+		{name: "overload is never remembered", err: ErrOverloaded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newFailureCache(5*time.Second, time.Minute, clock.Now)
+
+			c.Set("key", tt.err)
+			clock.Advance(tt.advance)
+
+			got := c.Get("key")
+			if tt.remembered && !errors.Is(got, tt.err) {
+				t.Errorf("Get = %v, want %v", got, tt.err)
+			}
+			if !tt.remembered && got != nil {
+				t.Errorf("Get = %v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestFailureCacheDisabledWindow(t *testing.T) {
+	c := newFailureCache(0, time.Minute, time.Now)
+	c.Set("key", errors.New("upstream failed"))
+	if err := c.Get("key"); err != nil {
+		t.Fatalf("Get = %v, want nil with failure caching disabled", err)
+	}
+}
+
+func TestFailureCacheSweepsExpiredEntries(t *testing.T) {
+	clock := newFakeClock()
+	c := newFailureCache(5*time.Second, time.Minute, clock.Now)
+	for i := 0; i < minSweepSize; i++ {
+		c.Set(strconv.Itoa(i), errors.New("upstream failed"))
+	}
+
+	clock.Advance(10 * time.Second)
+	c.Set("fresh", errors.New("upstream failed"))
+
+	if got := len(c.items); got != 1 {
+		t.Fatalf("failure cache holds %d entries after a sweep, want 1", got)
+	}
+}
+
+func TestLookupCeiling(t *testing.T) {
+	tests := []struct {
+		name           string
+		ceiling        time.Duration
+		callerDeadline time.Duration
+		want           error
+	}{
+		{name: "ceiling fires before a long caller deadline", ceiling: 50 * time.Millisecond, callerDeadline: time.Hour, want: ErrLookupTimeout},
+		{name: "caller deadline fires first", ceiling: time.Hour, callerDeadline: 50 * time.Millisecond, want: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			})
+			s := newTestService(t, google.option(), WithMaxLookupDuration(tt.ceiling), WithFailureTTLs(0, 0))
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.callerDeadline)
+			defer cancel()
+			start := time.Now()
+			_, err := s.Geocode(ctx, "Praça da Sé")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Geocode = %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("Geocode returned after %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package geocode
+
+import "unsafe"
+
+// entryOverhead approximates the fixed cost of a cache entry: the item itself, the key's string
+// header and a share of the map's buckets. It does not depend on the entry's content.
+const entryOverhead = int(unsafe.Sizeof(cacheItem{})+unsafe.Sizeof("")) + 48
+
+// entrySize estimates the memory held by a cache entry: the fixed overhead plus the bytes of its
+// key and of the strings it references. Enricher fields are counted by key only, since their values
+// are opaque.
+func entrySize(key string, value Result) int {
+	size := entryOverhead + len(key) +
+		len(value.Address) + len(value.Source) + len(value.CountryCode) + len(value.PlusCode) +
+		len(value.Precision) + len(value.Query)
+	for _, warning := range value.Warnings {
+		size += int(unsafe.Sizeof("")) + len(warning)
+	}
+	if c := value.Components; c != nil {
+		size += int(unsafe.Sizeof(*c)) + len(c.StreetNumber) + len(c.Route) +
+			len(c.AdministrativeAreaLevel1) + len(c.AdministrativeAreaLevel2) + len(c.Locality) +
+			len(c.Sublocality) + len(c.Neighborhood) + len(c.PostalCode) + len(c.Country)
+	}
+	for field := range value.Extra {
+		size += 2*int(unsafe.Sizeof("")) + len(field)
+	}
+	if value.Debug != nil {
+		size += int(unsafe.Sizeof(*value.Debug)) + len(value.Debug.Upstream)
+	}
+	return size
+}
+
+// put stores item under key and keeps the shard's byte estimate in step. The shard must be locked.
+func (s *cacheShard) put(key string, item cacheItem) {
+	if old, ok := s.items[key]; ok {
+		s.bytes -= entrySize(key, old.value)
+	}
+	s.items[key] = item
+	s.bytes += entrySize(key, item.value)
+}
+
+// remove deletes key and its share of the byte estimate. The shard must be locked.
+func (s *cacheShard) remove(key string) {
+	if old, ok := s.items[key]; ok {
+		s.bytes -= entrySize(key, old.value)
+		delete(s.items, key)
+	}
+}
+
+// EstimatedBytes approximates the memory held by the cache entries. Each shard keeps a running
+// total updated as entries are stored and evicted, so reading it only sums the shards. It ignores
+// allocator and map growth overhead and is meant for capacity planning, not exact accounting.
+func (c *cache) EstimatedBytes() int {
+	total := 0
+	for i := range c.shards {
+		shard := &c.shards[i]
+		shard.mu.RLock()
+		total += shard.bytes
+		shard.mu.RUnlock()
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package geocode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEstimatedBytesGrowsWithEntries(t *testing.T) {
+	clock := newFakeClock()
+	c := newCache(time.Minute, clock.Now, 4)
+	if got := c.EstimatedBytes(); got != 0 {
+		t.Fatalf("empty cache estimate = %d, want 0", got)
+	}
+
+	previous := 0
+	for i := 0; i < 50; i++ {
+		c.Set(fmt.Sprintf("rua %d, são paulo", i), Result{Address: fmt.Sprintf("Rua %d, São Paulo - SP, Brazil", i), Source: "google"})
+		got := c.EstimatedBytes()
+		if got <= previous {
+			t.Fatalf("estimate after %d entries = %d, want more than %d", i+1, got, previous)
+		}
+		previous = got
+	}
+}
+
+func TestEstimatedBytesTracksUpdatesAndEvictions(t *testing.T) {
+	short := Result{Address: "Sé", Source: "google"}
+	long := Result{Address: "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil", Source: "google",
+		Components: &Components{Route: "Praça da Sé", Country: "Brazil"}, Warnings: []string{WarningNoCountry}}
+
+	tests := []struct {
+		name string
+		run  func(c *cache, clock *fakeClock)
+		want int
+	}{
+		{name: "one entry", run: func(c *cache, _ *fakeClock) { c.Set("sé", short) }, want: entrySize("sé", short)},
+		{name: "overwritten entry", run: func(c *cache, _ *fakeClock) {
+			c.Set("sé", short)
+			c.Set("sé", long)
+		}, want: entrySize("sé", long)},
+		{name: "expired entry read", run: func(c *cache, clock *fakeClock) {
+			c.Set("sé", long)
+			clock.Advance(time.Hour)
+			c.Get("sé")
+		}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newCache(time.Minute, clock.Now, 4)
+			tt.run(c, clock)
+			if got := c.EstimatedBytes(); got != tt.want {
+				t.Errorf("estimate = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
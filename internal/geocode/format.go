@@ -0,0 +1,75 @@
+package geocode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultAddressOrder is the component order used to rebuild addresses when none is given: street
+// first, then the area, postal code and country.
+var defaultAddressOrder = []string{
+	"route", "street_number", "sublocality", "locality", "administrative_area_level_1", "postal_code", "country",
+}
+
+// component returns the value of the component with the given JSON name and whether the name is
+// known.
+func (c *Components) component(name string) (string, bool) {
+	switch name {
+	case "street_number":
+		return c.StreetNumber, true
+	case "route":
+		return c.Route, true
+	case "neighborhood":
+		return c.Neighborhood, true
+	case "sublocality":
+		return c.Sublocality, true
+	case "locality":
+		return c.Locality, true
+	case "administrative_area_level_2":
+		return c.AdministrativeAreaLevel2, true
+	case "administrative_area_level_1":
+		return c.AdministrativeAreaLevel1, true
+	case "postal_code":
+		return c.PostalCode, true
+	case "country":
+		return c.Country, true
+	default:
+		return "", false
+	}
+}
+
+// formatAddress joins the components named by order, skipping missing ones. It returns "" when
+// order is empty or none of the components is present, so the caller keeps the provider's address.
+func formatAddress(components *Components, order []string) string {
+	if len(order) == 0 || components == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(order))
+	for _, name := range order {
+		if value, _ := components.component(name); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WithComponentAddress rebuilds each result's address from its components, joined with ", " in the
+// given order of component names (such as "route", "locality" or "postal_code"). Missing components
+// are skipped, and results without any of them keep Google's formatted address. With no names, a
+// street, area, postal code and country order is used. By default the formatted address is passed
+// through unchanged.
+func WithComponentAddress(order ...string) Option {
+	return func(o *options) error {
+		if len(order) == 0 {
+			order = defaultAddressOrder
+		}
+		var known Components
+		for _, name := range order {
+			if _, ok := known.component(name); !ok {
+				return fmt.Errorf("unknown address component %q", name)
+			}
+		}
+		o.addressOrder = append([]string(nil), order...)
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestComponentAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "passthrough by default", want: "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil"},
+		{name: "default order", opts: []Option{WithComponentAddress()},
+			want: "Praça da Sé, Sé, SP, 01001-000, Brazil"},
+		{name: "custom order", opts: []Option{WithComponentAddress("postal_code", "administrative_area_level_2", "country")},
+			want: "01001-000, São Paulo, Brazil"},
+		{name: "missing components are skipped", opts: []Option{WithComponentAddress("street_number", "route", "neighborhood", "country")},
+			want: "Praça da Sé, Brazil"},
+		{name: "no component present keeps Google's address", opts: []Option{WithComponentAddress("street_number", "neighborhood")},
+			want: "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+			s := newTestService(t, append([]Option{google.option()}, tt.opts...)...)
+
+			result, err := s.Geocode(context.Background(), "Praça da Sé")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Address != tt.want {
+				t.Errorf("address = %q, want %q", result.Address, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithComponentAddressRejectsUnknownComponents(t *testing.T) {
+	if _, err := New(WithComponentAddress("route", "city")); err == nil {
+		t.Fatal("expected an error for an unknown component")
+	}
+}
@@ -0,0 +1,308 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const geocodeEndpoint = "https://maps.googleapis.com/maps/api/geocode/json"
+
+// ErrMissingAPIKey is returned before any request is made when the provider has neither an API key
+// nor premium credentials.
+var ErrMissingAPIKey = errors.New("google maps api key is not configured")
+
+// GoogleProvider resolves addresses using the Google Maps Geocoding API.
+type GoogleProvider struct {
+	apiKey string
+	client *http.Client
+
+	premium *premiumCredentials
+	channel string
+
+	debugMaxBytes int
+	quota         *dailyQuota
+	tieBreak      string
+	addressOrder  []string
+}
+
+// NewGoogleProvider creates a provider authenticated with apiKey.
+func NewGoogleProvider(apiKey string) *GoogleProvider {
+	return &GoogleProvider{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Name identifies the provider in results and diagnostics.
+func (p *GoogleProvider) Name() string {
+	return "google"
+}
+
+// Geocode queries the Google Maps Geocoding API for address and returns the top result.
+func (p *GoogleProvider) Geocode(ctx context.Context, address string) (Result, error) {
+	return p.query(ctx, url.Values{"address": {address}})
+}
+
+// GeocodeComponents queries the Google Maps Geocoding API using only a components filter such as
+// "postal_code:01001-000|country:BR" and returns the top result.
+func (p *GoogleProvider) GeocodeComponents(ctx context.Context, components string) (Result, error) {
+	return p.query(ctx, url.Values{"components": {components}})
+}
+
+// GeocodeFiltered queries the Google Maps Geocoding API for address restricted by a components
+// filter and returns the top result. Either may be empty, but not both.
+func (p *GoogleProvider) GeocodeFiltered(ctx context.Context, address, components string) (Result, error) {
+	params := url.Values{}
+	if address != "" {
+		params.Set("address", address)
+	}
+	if components != "" {
+		params.Set("components", components)
+	}
+	return p.query(ctx, params)
+}
+
+func (p *GoogleProvider) query(ctx context.Context, params url.Values) (Result, error) {
+	apiURL, err := p.authorize(geocodeEndpoint, params)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := p.quota.take(); err != nil {
+		return Result{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return Result{}, err
+	}
+
+	resp, err := p.do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, &UpstreamStatusError{API: "google maps api", StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var payload geocodeResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Result{}, err
+	}
+
+	switch payload.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return Result{}, ErrNoResults
+	default:
+		return Result{}, &APIStatusError{API: "google maps api", Status: payload.Status, Message: payload.ErrorMessage}
+	}
+
+	if len(payload.Results) == 0 {
+		return Result{}, ErrNoResults
+	}
+
+	top := pickResult(payload.Results, p.tieBreak)
+	result := Result{
+		Address:     resultAddress(top),
+		Latitude:    top.Geometry.Location.Lat,
+		Longitude:   top.Geometry.Location.Lng,
+		CountryCode: countryCode(top.AddressComponents),
+		PlusCode:    top.PlusCode.GlobalCode,
+		Components:  parseComponents(top.AddressComponents),
+		Source:      p.Name(),
+
+		PartialMatch: top.PartialMatch,
+		Precision:    top.Geometry.LocationType,
+	}
+	if formatted := formatAddress(result.Components, p.addressOrder); formatted != "" {
+		result.Address = formatted
+	}
+	if result.CountryCode == "" {
+		result.Warnings = append(result.Warnings, WarningNoCountry)
+	}
+	if p.debugMaxBytes > 0 {
+		result.Debug = newDebugInfo(body, p.debugMaxBytes)
+	}
+	return result, nil
+}
+
+// geocodeResponse models the subset of the Google Geocoding API response that we require.
+type geocodeResponse struct {
+	Results      []geocodeResult `json:"results"`
+	Status       string          `json:"status"`
+	ErrorMessage string          `json:"error_message"`
+}
+
+// geocodeResult is a single entry of a Google Geocoding API response.
+type geocodeResult struct {
+	FormattedAddress  string             `json:"formatted_address"`
+	AddressComponents []addressComponent `json:"address_components"`
+	PartialMatch      bool               `json:"partial_match"`
+	PlusCode          struct {
+		GlobalCode   string `json:"global_code"`
+		CompoundCode string `json:"compound_code"`
+	} `json:"plus_code"`
+	Geometry struct {
+		Location struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		} `json:"location"`
+		LocationType string `json:"location_type"`
+	} `json:"geometry"`
+}
+
+// addressComponent is a single entry of a Google result's address_components.
+type addressComponent struct {
+	LongName  string   `json:"long_name"`
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+func (c addressComponent) hasType(want string) bool {
+	for _, t := range c.Types {
+		if t == want {
+			return true
+		}
+	}
+	return false
+}
+
+// countryCode returns the ISO 3166-1 alpha-2 code of the country component, if any.
+func countryCode(components []addressComponent) string {
+	for _, component := range components {
+		if component.hasType("country") {
+			return component.ShortName
+		}
+	}
+	return ""
+}
+
+// resultAddress returns the formatted address of r. Results for remote locations may come with
+// little more than coordinates and a plus code; their address falls back to the plus code, the
+// compound form (such as "CWC8+R9 Mountain View") when available.
+func resultAddress(r geocodeResult) string {
+	switch {
+	case r.FormattedAddress != "":
+		return r.FormattedAddress
+	case r.PlusCode.CompoundCode != "":
+		return r.PlusCode.CompoundCode
+	default:
+		return r.PlusCode.GlobalCode
+	}
+}
+
+// parseComponents collects the address components of a result. It returns nil when Google
+// reported none of them.
+func parseComponents(components []addressComponent) *Components {
+	var parsed Components
+	for _, component := range components {
+		switch {
+		case component.hasType("street_number"):
+			parsed.StreetNumber = component.LongName
+		case component.hasType("route"):
+			parsed.Route = component.LongName
+		case component.hasType("postal_code"):
+			parsed.PostalCode = component.LongName
+		case component.hasType("country"):
+			parsed.Country = component.LongName
+		case component.hasType("administrative_area_level_1"):
+			parsed.AdministrativeAreaLevel1 = component.ShortName
+		case component.hasType("administrative_area_level_2"):
+			parsed.AdministrativeAreaLevel2 = component.LongName
+		case component.hasType("locality"):
+			parsed.Locality = component.LongName
+		case component.hasType("sublocality"):
+			parsed.Sublocality = component.LongName
+		case component.hasType("neighborhood"):
+			parsed.Neighborhood = component.LongName
+		}
+	}
+	if parsed == (Components{}) {
+		return nil
+	}
+	return &parsed
+}
+
+// maxDrainBytes bounds how much of an unread response body is discarded so the connection can be
+// reused without reading arbitrarily large error pages.
+const maxDrainBytes = 64 << 10
+
+// closeBody drains and closes a response body. It is deferred right after a successful Do so the
+// body is released on every return path, including early errors.
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	_ = body.Close()
+}
+
+// UpstreamStatusError reports a non-200 HTTP status returned by a Google Maps API, so callers can
+// tell rate limiting from server failures with errors.As.
+type UpstreamStatusError struct {
+	API        string
+	StatusCode int
+}
+
+func (e *UpstreamStatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.API, e.StatusCode)
+}
+
+// APIStatusError reports a non-OK status, such as OVER_QUERY_LIMIT or REQUEST_DENIED, in the body of
+// a Google Maps API response.
+type APIStatusError struct {
+	API     string
+	Status  string
+	Message string
+}
+
+func (e *APIStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s error: %s", e.API, e.Message)
+	}
+	return fmt.Sprintf("%s status: %s", e.API, e.Status)
+}
+
+// Categories of upstream failures returned by ErrorCategory.
+const (
+	CategoryQuota       = "quota"
+	CategoryDenied      = "denied"
+	CategoryUpstream5xx = "upstream_5xx"
+)
+
+// ErrorCategory classifies an upstream failure as quota exhaustion, a denied request or a Google
+// server error. Other errors, including ErrNoResults, have no category.
+func ErrorCategory(err error) string {
+	var statusErr *UpstreamStatusError
+	var apiErr *APIStatusError
+	switch {
+	case errors.Is(err, ErrQuotaCapReached):
+		return CategoryQuota
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusTooManyRequests {
+			return CategoryQuota
+		}
+		if statusErr.StatusCode >= http.StatusInternalServerError {
+			return CategoryUpstream5xx
+		}
+	case errors.As(err, &apiErr):
+		switch apiErr.Status {
+		case "OVER_QUERY_LIMIT", "OVER_DAILY_LIMIT":
+			return CategoryQuota
+		case "REQUEST_DENIED":
+			return CategoryDenied
+		}
+	}
+	return ""
+}
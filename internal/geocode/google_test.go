@@ -0,0 +1,376 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeGoogle serves handler from an httptest.Server standing in for the Google Maps APIs.
+type fakeGoogle struct {
+	server   *httptest.Server
+	requests atomic.Int32
+	// conns counts the connections opened to the server.
+	conns atomic.Int32
+}
+
+func newFakeGoogle(t *testing.T, handler http.HandlerFunc) *fakeGoogle {
+	t.Helper()
+	f := &fakeGoogle{}
+	f.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		handler(w, r)
+	}))
+	f.server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			f.conns.Add(1)
+		}
+	}
+	f.server.Start()
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// option routes every request meant for Google to the fake server through the real transport.
+func (f *fakeGoogle) option() Option {
+	return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.URL.Scheme = "http"
+			req.URL.Host = f.server.Listener.Addr().String()
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+// respond answers every request with status and body.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+// sePayload is a trimmed Geocoding API response for Praça da Sé in São Paulo.
+const sePayload = `{
+  "status": "OK",
+  "results": [{
+    "formatted_address": "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil",
+    "address_components": [
+      {"long_name": "Praça da Sé", "short_name": "Praça da Sé", "types": ["route"]},
+      {"long_name": "Sé", "short_name": "Sé", "types": ["sublocality_level_1", "sublocality", "political"]},
+      {"long_name": "São Paulo", "short_name": "São Paulo", "types": ["administrative_area_level_2", "political"]},
+      {"long_name": "São Paulo", "short_name": "SP", "types": ["administrative_area_level_1", "political"]},
+      {"long_name": "Brazil", "short_name": "BR", "types": ["country", "political"]},
+      {"long_name": "01001-000", "short_name": "01001-000", "types": ["postal_code"]}
+    ],
+    "geometry": {"location": {"lat": -23.5505191, "lng": -46.6333094}, "location_type": "GEOMETRIC_CENTER"},
+    "place_id": "ChIJ0WGkg4FEzpQRrlsz_whLqZs"
+  }]
+}`
+
+func TestGoogleCancellationAbortsOutboundRequest(t *testing.T) {
+	arrived := make(chan struct{})
+	observed := make(chan struct{})
+	google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+			close(observed)
+		case <-time.After(5 * time.Second):
+		}
+	})
+	s := newTestService(t, google.option())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := s.Geocode(ctx, "Praça da Sé")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Geocode = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Geocode returned after %s, want prompt cancellation", elapsed)
+	}
+	select {
+	case <-observed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the outbound request did not observe the cancellation")
+	}
+}
+
+func TestCanceledContextStopsTheChain(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	first := &stubProvider{name: "first", answer: func(context.Context, string) (Result, error) {
+		return Result{}, ErrNoResults
+	}}
+	s := newTestService(t, google.option(), WithProviders(first))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Geocode(ctx, "Praça da Sé"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Geocode = %v, want context.Canceled", err)
+	}
+	if first.calls.Load() != 0 || google.requests.Load() != 0 {
+		t.Fatalf("providers were called with a canceled context: first=%d google=%d", first.calls.Load(), google.requests.Load())
+	}
+}
+
+func TestGoogleErrorResponsesReleaseTheConnection(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusInternalServerError, `{"error": "backend unavailable"}`))
+	s := newTestService(t, google.option(), WithFailureTTLs(0, 0))
+
+	for i := 0; i < 3; i++ {
+		var statusErr *UpstreamStatusError
+		if _, err := s.Geocode(context.Background(), "Praça da Sé"); !errors.As(err, &statusErr) {
+			t.Fatalf("Geocode = %v, want an *UpstreamStatusError", err)
+		}
+	}
+	// Drained and closed bodies let every request reuse the first connection.
+	if got := google.conns.Load(); got != 1 {
+		t.Fatalf("opened %d connections for 3 requests, want 1", got)
+	}
+}
+
+func TestMissingAPIKeySkipsOutboundCalls(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	s, err := New(google.option())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{name: "geocode", call: func() error {
+			_, err := s.Geocode(context.Background(), "Praça da Sé")
+			return err
+		}},
+		{name: "postal code", call: func() error {
+			_, err := s.GeocodePostalCode(context.Background(), "01001-000", "BR")
+			return err
+		}},
+		{name: "time zone", call: func() error {
+			_, err := s.TimeZone(context.Background(), -23.55, -46.63, time.Now())
+			return err
+		}},
+	}
+	for _, c := range calls {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.call(); !errors.Is(err, ErrMissingAPIKey) {
+				t.Fatalf("error = %v, want ErrMissingAPIKey", err)
+			}
+		})
+	}
+	if got := google.requests.Load(); got != 0 {
+		t.Fatalf("made %d outbound requests without an API key", got)
+	}
+}
+
+func TestGoogleCountryCode(t *testing.T) {
+	const noCountry = `{"status": "OK", "results": [{
+		"formatted_address": "Atlantic Ocean",
+		"address_components": [{"long_name": "Atlantic Ocean", "short_name": "Atlantic Ocean", "types": ["natural_feature"]}],
+		"geometry": {"location": {"lat": -14.5994, "lng": -28.6731}, "location_type": "APPROXIMATE"}
+	}]}`
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     string
+		wantWarnings []string
+	}{
+		{name: "with country", body: sePayload, wantCode: "BR"},
+		{name: "without country", body: noCountry, wantWarnings: []string{WarningNoCountry}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, tt.body))
+			s := newTestService(t, google.option())
+
+			result, err := s.Geocode(context.Background(), "some place")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.CountryCode != tt.wantCode || !reflect.DeepEqual(result.Warnings, tt.wantWarnings) {
+				t.Errorf("country code %q with warnings %v, want %q with %v", result.CountryCode, result.Warnings, tt.wantCode, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestGoogleZeroResults(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "ZERO_RESULTS status", body: `{"status": "ZERO_RESULTS", "results": []}`},
+		{name: "OK without results", body: `{"status": "OK", "results": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, tt.body))
+			s := newTestService(t, google.option())
+
+			if _, err := s.Geocode(context.Background(), "Rua Inexistente, 999"); !errors.Is(err, ErrNoResults) {
+				t.Fatalf("Geocode = %v, want ErrNoResults", err)
+			}
+		})
+	}
+}
+
+func TestGoogleUpstreamStatusError(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			google := newFakeGoogle(t, respond(status, ""))
+			s := newTestService(t, google.option(), WithFailureTTLs(0, 0))
+
+			_, err := s.Geocode(context.Background(), "Praça da Sé")
+			var statusErr *UpstreamStatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+				t.Fatalf("Geocode = %v, want an *UpstreamStatusError with status %d", err, status)
+			}
+		})
+	}
+}
+
+func TestParseComponentsAdministrativeAreas(t *testing.T) {
+	tests := []struct {
+		name       string
+		components string
+		want       *Components
+		wantJSON   string
+	}{
+		{
+			name: "US county and neighborhood",
+			components: `[
+				{"long_name": "1600", "short_name": "1600", "types": ["street_number"]},
+				{"long_name": "Amphitheatre Parkway", "short_name": "Amphitheatre Pkwy", "types": ["route"]},
+				{"long_name": "Shoreline", "short_name": "Shoreline", "types": ["neighborhood", "political"]},
+				{"long_name": "Mountain View", "short_name": "Mountain View", "types": ["locality", "political"]},
+				{"long_name": "Santa Clara County", "short_name": "Santa Clara County", "types": ["administrative_area_level_2", "political"]},
+				{"long_name": "California", "short_name": "CA", "types": ["administrative_area_level_1", "political"]}
+			]`,
+			want: &Components{StreetNumber: "1600", Route: "Amphitheatre Parkway", Neighborhood: "Shoreline", Locality: "Mountain View",
+				AdministrativeAreaLevel2: "Santa Clara County", AdministrativeAreaLevel1: "CA"},
+			wantJSON: `{"street_number":"1600","route":"Amphitheatre Parkway","administrative_area_level_1":"CA",` +
+				`"administrative_area_level_2":"Santa Clara County","locality":"Mountain View","neighborhood":"Shoreline"}`,
+		},
+		{
+			name: "Brazilian municipality and sublocality",
+			components: `[
+				{"long_name": "Sé", "short_name": "Sé", "types": ["sublocality_level_1", "sublocality", "political"]},
+				{"long_name": "São Paulo", "short_name": "São Paulo", "types": ["administrative_area_level_2", "political"]},
+				{"long_name": "São Paulo", "short_name": "SP", "types": ["administrative_area_level_1", "political"]}
+			]`,
+			want:     &Components{Sublocality: "Sé", AdministrativeAreaLevel2: "São Paulo", AdministrativeAreaLevel1: "SP"},
+			wantJSON: `{"administrative_area_level_1":"SP","administrative_area_level_2":"São Paulo","sublocality":"Sé"}`,
+		},
+		{
+			name: "without the extra levels",
+			components: `[
+				{"long_name": "Brasília", "short_name": "Brasília", "types": ["locality", "political"]},
+				{"long_name": "Distrito Federal", "short_name": "DF", "types": ["administrative_area_level_1", "political"]}
+			]`,
+			want:     &Components{Locality: "Brasília", AdministrativeAreaLevel1: "DF"},
+			wantJSON: `{"administrative_area_level_1":"DF","locality":"Brasília"}`,
+		},
+		{
+			name:       "none",
+			components: `[{"long_name": "Earth", "short_name": "Earth", "types": ["natural_feature"]}]`,
+			wantJSON:   `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var components []addressComponent
+			if err := json.Unmarshal([]byte(tt.components), &components); err != nil {
+				t.Fatalf("decode fixture: %v", err)
+			}
+			got := parseComponents(components)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseComponents = %+v, want %+v", got, tt.want)
+			}
+			encoded, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if string(encoded) != tt.wantJSON {
+				t.Errorf("JSON = %s\nwant %s", encoded, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestGooglePlusCodeOnlyResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		opts   []Option
+		want   Result
+	}{
+		{
+			name: "compound code without components",
+			result: `{"geometry": {"location": {"lat": -3.4653, "lng": -62.2159}, "location_type": "GEOMETRIC_CENTER"},
+				"plus_code": {"compound_code": "GQM7+V9 Amazonas", "global_code": "67XJGQM7+V9"}, "address_components": []}`,
+			want: Result{Address: "GQM7+V9 Amazonas", Latitude: -3.4653, Longitude: -62.2159, PlusCode: "67XJGQM7+V9",
+				Precision: "GEOMETRIC_CENTER", Warnings: []string{WarningNoCountry}},
+		},
+		{
+			name:   "global code only",
+			result: `{"geometry": {"location": {"lat": -3.4653, "lng": -62.2159}}, "plus_code": {"global_code": "67XJGQM7+V9"}}`,
+			want:   Result{Address: "67XJGQM7+V9", Latitude: -3.4653, Longitude: -62.2159, PlusCode: "67XJGQM7+V9", Warnings: []string{WarningNoCountry}},
+		},
+		{
+			name: "component address format falls back to the plus code",
+			result: `{"geometry": {"location": {"lat": -3.4653, "lng": -62.2159}},
+				"plus_code": {"compound_code": "GQM7+V9 Amazonas", "global_code": "67XJGQM7+V9"}}`,
+			opts: []Option{WithComponentAddress("route", "locality", "country")},
+			want: Result{Address: "GQM7+V9 Amazonas", Latitude: -3.4653, Longitude: -62.2159, PlusCode: "67XJGQM7+V9", Warnings: []string{WarningNoCountry}},
+		},
+		{
+			name: "country only",
+			result: `{"geometry": {"location": {"lat": -3.4653, "lng": -62.2159}},
+				"plus_code": {"compound_code": "GQM7+V9 Amazonas", "global_code": "67XJGQM7+V9"},
+				"address_components": [{"long_name": "Brazil", "short_name": "BR", "types": ["country", "political"]}]}`,
+			want: Result{Address: "GQM7+V9 Amazonas", Latitude: -3.4653, Longitude: -62.2159, PlusCode: "67XJGQM7+V9",
+				CountryCode: "BR", Components: &Components{Country: "Brazil"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, `{"status": "OK", "results": [`+tt.result+`]}`))
+			s := newTestService(t, append([]Option{google.option()}, tt.opts...)...)
+
+			got, err := s.google.Geocode(context.Background(), "-3.4653,-62.2159")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			tt.want.Source = "google"
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("result = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
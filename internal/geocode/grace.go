@@ -0,0 +1,62 @@
+package geocode
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// MaxGraceFactor is the largest factor accepted by StartGracePeriod.
+	MaxGraceFactor = 100
+	// maxGraceExtension bounds how far a grace period pushes back a single entry's expiry, so the
+	// extension cannot overflow whatever the entry's TTL.
+	maxGraceExtension = 365 * 24 * time.Hour
+)
+
+// ErrInvalidGracePeriod is returned for a grace period that would not extend anything, or whose
+// factor is out of range.
+var ErrInvalidGracePeriod = errors.New("grace period factor must be between 1 and 100 and its duration positive")
+
+// GracePeriod temporarily stretches the lifetime of in-memory cache entries, for example to lean on
+// the cache during a provider's announced maintenance window.
+type GracePeriod struct {
+	// Factor multiplies the TTL of every entry, existing and new.
+	Factor float64 `json:"factor"`
+	// Until is when the grace period ends and entries revert to their normal lifetime.
+	Until time.Time `json:"until"`
+}
+
+// StartGracePeriod multiplies the TTL of every in-memory cache entry by factor until duration has
+// elapsed, replacing any grace period in progress. Entries already stored benefit as well as new
+// ones, since the factor is applied when an entry is read; entries that outlived their normal TTL
+// expire again once the period ends. A factor of 1 ends the current grace period early; factors
+// above MaxGraceFactor, and non-finite ones, are rejected. The secondary cache, if any, keeps its
+// own expiry.
+func (s *Service) StartGracePeriod(factor float64, duration time.Duration) (GracePeriod, error) {
+	// Written so that NaN, which fails every comparison, is rejected too.
+	if !(factor >= 1 && factor <= MaxGraceFactor) || duration <= 0 {
+		return GracePeriod{}, ErrInvalidGracePeriod
+	}
+	grace := &GracePeriod{Factor: factor, Until: s.memory.now().Add(duration)}
+	s.memory.grace.Store(grace)
+	return *grace, nil
+}
+
+// activeGrace returns the grace period in effect at now, if any.
+func (c *cache) activeGrace(now time.Time) (GracePeriod, bool) {
+	grace := c.grace.Load()
+	if grace == nil || grace.Factor <= 1 || !now.Before(grace.Until) {
+		return GracePeriod{}, false
+	}
+	return *grace, true
+}
+
+// expired reports whether item has expired at now, stretching its TTL during a grace period.
+func (c *cache) expired(item cacheItem, now time.Time) bool {
+	expires := item.expires
+	if grace, ok := c.activeGrace(now); ok {
+		extension := float64(item.ttl) * (grace.Factor - 1)
+		expires = expires.Add(time.Duration(min(extension, float64(maxGraceExtension))))
+	}
+	return now.After(expires)
+}
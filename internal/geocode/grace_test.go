@@ -0,0 +1,124 @@
+package geocode
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGracePeriodExtendsExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		factor   float64
+		duration time.Duration
+		// storeAfterStart stores the entry once the grace period has started instead of before.
+		storeAfterStart bool
+		// checks are offsets from the store time and whether the entry should still be served.
+		checks []struct {
+			at      time.Duration
+			wantHit bool
+		}
+	}{
+		{name: "existing entry is stretched", factor: 3, duration: 10 * time.Minute, checks: []struct {
+			at      time.Duration
+			wantHit bool
+		}{{at: 2 * time.Minute, wantHit: true}, {at: 3 * time.Minute, wantHit: true}, {at: 3*time.Minute + time.Second}}},
+		{name: "new entry is stretched", factor: 3, duration: 10 * time.Minute, storeAfterStart: true, checks: []struct {
+			at      time.Duration
+			wantHit bool
+		}{{at: 2 * time.Minute, wantHit: true}, {at: 3*time.Minute + time.Second}}},
+		{name: "reverts when the period ends", factor: 10, duration: 90 * time.Second, checks: []struct {
+			at      time.Duration
+			wantHit bool
+		}{{at: 80 * time.Second, wantHit: true}, {at: 90 * time.Second}}},
+		{name: "factor 1 ends the period", factor: 1, duration: time.Hour, checks: []struct {
+			at      time.Duration
+			wantHit bool
+		}{{at: time.Minute, wantHit: true}, {at: time.Minute + time.Second}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			s := newTestService(t, WithClock(clock.Now), WithCacheTTL(time.Minute))
+			if !tt.storeAfterStart {
+				s.memory.Set("praça da sé", Result{Address: "Praça da Sé"})
+			}
+			if _, err := s.StartGracePeriod(tt.factor, tt.duration); err != nil {
+				t.Fatalf("StartGracePeriod: %v", err)
+			}
+			if tt.storeAfterStart {
+				s.memory.Set("praça da sé", Result{Address: "Praça da Sé"})
+			}
+
+			start := clock.Now()
+			for _, check := range tt.checks {
+				clock.Advance(start.Add(check.at).Sub(clock.Now()))
+				if _, hit := s.memory.Get("praça da sé"); hit != check.wantHit {
+					t.Fatalf("hit after %s = %v, want %v", check.at, hit, check.wantHit)
+				}
+			}
+		})
+	}
+}
+
+func TestGracePeriodInCacheStats(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestService(t, WithClock(clock.Now))
+	if s.CacheStats().GracePeriod != nil {
+		t.Fatal("grace period reported before one was started")
+	}
+	grace, err := s.StartGracePeriod(2, time.Hour)
+	if err != nil {
+		t.Fatalf("StartGracePeriod: %v", err)
+	}
+	if got := s.CacheStats().GracePeriod; got == nil || *got != grace {
+		t.Errorf("stats grace period = %v, want %v", got, grace)
+	}
+	clock.Advance(time.Hour)
+	if got := s.CacheStats().GracePeriod; got != nil {
+		t.Errorf("stats grace period after it ended = %v, want none", got)
+	}
+}
+
+func TestStartGracePeriodRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		factor   float64
+		duration time.Duration
+	}{
+		{name: "factor below 1", factor: 0.5, duration: time.Hour},
+		{name: "zero factor", factor: 0, duration: time.Hour},
+		{name: "factor above the maximum", factor: MaxGraceFactor + 1, duration: time.Hour},
+		{name: "huge factor", factor: 1e308, duration: time.Hour},
+		{name: "infinite factor", factor: math.Inf(1), duration: time.Hour},
+		{name: "NaN factor", factor: math.NaN(), duration: time.Hour},
+		{name: "zero duration", factor: 2},
+		{name: "negative duration", factor: 2, duration: -time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, err := s.StartGracePeriod(tt.factor, tt.duration); !errors.Is(err, ErrInvalidGracePeriod) {
+				t.Errorf("StartGracePeriod(%v, %s) = %v, want ErrInvalidGracePeriod", tt.factor, tt.duration, err)
+			}
+			if s.CacheStats().GracePeriod != nil {
+				t.Error("a rejected grace period took effect")
+			}
+		})
+	}
+}
+
+func TestGracePeriodExtensionIsBounded(t *testing.T) {
+	clock := newFakeClock()
+	// A TTL this long multiplied by the maximum factor would overflow time.Duration.
+	s := newTestService(t, WithClock(clock.Now), WithCacheTTL(200*365*24*time.Hour))
+	s.memory.Set("praça da sé", Result{Address: "Praça da Sé"})
+	if _, err := s.StartGracePeriod(MaxGraceFactor, 24*time.Hour); err != nil {
+		t.Fatalf("StartGracePeriod: %v", err)
+	}
+	clock.Advance(time.Hour)
+	if _, hit := s.memory.Get("praça da sé"); !hit {
+		t.Error("entry expired during a grace period with a long TTL")
+	}
+}
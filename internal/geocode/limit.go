@@ -0,0 +1,38 @@
+package geocode
+
+import "errors"
+
+// ErrOverloaded is returned without calling any provider when the configured number of upstream
+// lookups is already in flight, or when an AdaptiveProvider could not call it before the caller's
+// deadline.
+var ErrOverloaded = errors.New("too many lookups in flight")
+
+// inflightLimit bounds concurrent upstream lookups. Callers that find it full fail immediately
+// instead of queuing, so a slow upstream cannot pile up blocked requests. A nil limit is unbounded.
+type inflightLimit chan struct{}
+
+func newInflightLimit(max int) inflightLimit {
+	if max <= 0 {
+		return nil
+	}
+	return make(inflightLimit, max)
+}
+
+// tryAcquire takes a slot if one is free. Every successful call must be paired with release.
+func (l inflightLimit) tryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l inflightLimit) release() {
+	if l != nil {
+		<-l
+	}
+}
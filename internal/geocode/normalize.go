@@ -0,0 +1,44 @@
+package geocode
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalization shows how an address is prepared before it is looked up.
+type Normalization struct {
+	Raw          string `json:"raw"`
+	Preprocessed string `json:"preprocessed"`
+	Key          string `json:"key"`
+}
+
+// Normalize reports the steps Geocode applies to rawAddress, ending with the cache key it would use,
+// including the default country when one is configured. It never calls a provider.
+func (s *Service) Normalize(rawAddress string) Normalization {
+	key := normalizeAddress(rawAddress)
+	if key != "" {
+		key = s.augment(key)
+	}
+	return Normalization{
+		Raw:          rawAddress,
+		Preprocessed: strings.TrimSpace(rawAddress),
+		Key:          key,
+	}
+}
+
+// queryFingerprint reduces an address to its lowercased letters and digits. Unlike the cache key
+// it keeps diacritics and every word, so it tells apart addresses that normalization may merge.
+func queryFingerprint(address string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, address)
+}
+
+// compatibleQueries reports whether a result looked up for cached may be served for query. Results
+// stored without a query, such as those restored from older snapshots, are accepted.
+func compatibleQueries(cached, query string) bool {
+	return cached == "" || queryFingerprint(cached) == queryFingerprint(query)
+}
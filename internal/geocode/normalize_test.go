@@ -0,0 +1,77 @@
+package geocode
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeKeyMatchesGeocode(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		raw     string
+		wantKey string
+	}{
+		{name: "plain", raw: "Praça da Sé, São Paulo", wantKey: "praça da sé, são paulo"},
+		{name: "padded and mixed case", raw: "  AVENIDA Paulista, 1000 \t", wantKey: "avenida paulista, 1000"},
+		{name: "default country", opts: []Option{WithDefaultCountry("Brasil", "Brazil")}, raw: "Praça da Sé, São Paulo", wantKey: "praça da sé, são paulo, brasil"},
+		{name: "country already present", opts: []Option{WithDefaultCountry("Brasil", "Brazil")}, raw: "Praça da Sé, São Paulo, Brazil", wantKey: "praça da sé, são paulo, brazil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63})}
+			s := newTestService(t, append(tt.opts, WithProviders(provider))...)
+
+			n := s.Normalize(tt.raw)
+			if n.Raw != tt.raw || n.Key != tt.wantKey {
+				t.Fatalf("Normalize = %+v, want key %q", n, tt.wantKey)
+			}
+			if provider.calls.Load() != 0 {
+				t.Fatal("Normalize called a provider")
+			}
+
+			if _, err := s.Geocode(context.Background(), tt.raw); err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if !s.memory.contains(n.Key) {
+				t.Errorf("Geocode did not cache the result under %q", n.Key)
+			}
+		})
+	}
+}
+
+func TestCollisionGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		guard      bool
+		second     string
+		wantSource string
+	}{
+		{name: "guard off serves the colliding entry", second: "Praça da Sé, Brasil", wantSource: "cache"},
+		{name: "guard on looks up a colliding address", guard: true, second: "Praça da Sé, Brasil", wantSource: "stub"},
+		{name: "guard on serves the same address", guard: true, second: "  PRAÇA DA SÉ ", wantSource: "cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{name: "stub", answer: func(_ context.Context, address string) (Result, error) {
+				return Result{Address: address, Source: "stub"}, nil
+			}}
+			s := newTestService(t, WithProviders(provider), WithDefaultCountry("Brasil", "Brazil"), WithCollisionGuard(tt.guard))
+
+			// Both addresses normalize to the same key once the default country is applied.
+			if first, second := s.Normalize("Praça da Sé").Key, s.Normalize(tt.second).Key; first != second {
+				t.Fatalf("keys %q and %q differ; the case does not collide", first, second)
+			}
+			if _, err := s.Geocode(context.Background(), "Praça da Sé"); err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			result, err := s.Geocode(context.Background(), tt.second)
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Source != tt.wantSource {
+				t.Errorf("second lookup source = %s, want %s", result.Source, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+package geocode
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"apigo/internal/metrics"
+)
+
+// DefaultCacheTTL is the lifetime of cache entries when none is configured.
+const DefaultCacheTTL = 30 * time.Minute
+
+// DefaultCacheShards is the number of independently locked cache shards when none is configured.
+const DefaultCacheShards = 16
+
+// DefaultMinAddressLength is the shortest normalized address, in characters, sent to providers
+// when no minimum is configured.
+const DefaultMinAddressLength = 3
+
+// Option configures a Service created with New.
+type Option func(*options) error
+
+// options collects the settings applied by Option values before the Service is assembled.
+type options struct {
+	apiKey        string
+	channel       string
+	premium       *premiumCredentials
+	debugMaxBytes int
+	transport     TransportSettings
+	roundTripper  []func(http.RoundTripper) http.RoundTripper
+	tieBreak      string
+	addressOrder  []string
+
+	cacheTTL           time.Duration
+	cacheShards        int
+	providerTTLs       map[string]time.Duration
+	precisionTTLs      map[string]time.Duration
+	now                func() time.Time
+	secondaryCache     Cache
+	statsWindow        time.Duration
+	canonicalCacheSize int
+	churnLimit         int
+	churnWindow        time.Duration
+	churnNotify        func(paused bool, newKeys int)
+	failureTTL         time.Duration
+	noResultsTTL       time.Duration
+	maxLookup          time.Duration
+
+	providerTimeout time.Duration
+	maxProviders    int
+	collisionGuard  bool
+	metrics         metrics.Sink
+	maxInflight     int
+	defaultCountry  []string
+	dailyCap        int
+	dailyCapReset   time.Duration
+	errorObserver   func(error)
+	deniedThreshold int
+	onFatal         func(error)
+
+	filter           *Filter
+	coordinateMode   string
+	minAddressLength int
+	enrichers        []ResultEnricher
+	providers        []Provider
+	decorators       []func(Provider) Provider
+}
+
+func defaultOptions() options {
+	return options{
+		transport:        DefaultTransportSettings,
+		tieBreak:         TieBreakFirst,
+		cacheTTL:         DefaultCacheTTL,
+		cacheShards:      DefaultCacheShards,
+		now:              time.Now,
+		statsWindow:      DefaultStatsWindow,
+		churnNotify:      func(bool, int) {},
+		failureTTL:       DefaultFailureTTL,
+		noResultsTTL:     DefaultNoResultsTTL,
+		maxLookup:        DefaultMaxLookupDuration,
+		coordinateMode:   CoordinateInputAllow,
+		minAddressLength: DefaultMinAddressLength,
+		metrics:          metrics.Nop{},
+		errorObserver:    func(error) {},
+	}
+}
+
+// WithAPIKey sets the key used to authenticate with the Google Maps APIs.
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) error {
+		o.apiKey = apiKey
+		return nil
+	}
+}
+
+// WithPremiumCredentials switches the Google provider to the premium client ID and URL signing
+// flow. The secret is the URL-safe base64 private key issued by Google.
+func WithPremiumCredentials(clientID, secret string) Option {
+	return func(o *options) error {
+		key, err := decodeSigningKey(secret)
+		if err != nil {
+			return fmt.Errorf("invalid signing secret: %w", err)
+		}
+		o.premium = &premiumCredentials{clientID: clientID, key: key}
+		return nil
+	}
+}
+
+// WithChannel sets the channel parameter sent with every Google request for usage reporting.
+func WithChannel(channel string) Option {
+	return func(o *options) error {
+		o.channel = channel
+		return nil
+	}
+}
+
+// WithDebugCapture keeps up to maxBytes of each raw Google response alongside the result, so it can
+// be inspected later even on cache hits. Zero disables capturing.
+func WithDebugCapture(maxBytes int) Option {
+	return func(o *options) error {
+		o.debugMaxBytes = maxBytes
+		return nil
+	}
+}
+
+// WithTransportSettings tunes the keep-alive and idle connection behavior of the connections to
+// the Google Maps APIs.
+func WithTransportSettings(settings TransportSettings) Option {
+	return func(o *options) error {
+		if settings.IdleConnTimeout < 0 || settings.KeepAlive < 0 || settings.MaxIdleConnsPerHost < 0 {
+			return fmt.Errorf("transport settings must not be negative")
+		}
+		o.transport = settings
+		return nil
+	}
+}
+
+// WithRoundTripper layers wrap over the transport used for requests to the Google Maps APIs, for
+// example to record and replay traffic in tests, add tracing headers or count requests. wrap
+// receives the transport built from the transport settings, or the previous wrapper's result, and
+// may ignore it to replace the transport entirely. Wrappers are applied in the order they were added.
+func WithRoundTripper(wrap func(next http.RoundTripper) http.RoundTripper) Option {
+	return func(o *options) error {
+		if wrap == nil {
+			return fmt.Errorf("round tripper wrapper must not be nil")
+		}
+		o.roundTripper = append(o.roundTripper, wrap)
+		return nil
+	}
+}
+
+// WithTieBreak selects how a result is chosen when Google returns several: TieBreakFirst (the
+// default) or TieBreakAddress for a choice that does not depend on the order of the response.
+func WithTieBreak(strategy string) Option {
+	return func(o *options) error {
+		switch strategy {
+		case TieBreakFirst, TieBreakAddress:
+			o.tieBreak = strategy
+			return nil
+		default:
+			return fmt.Errorf("unknown tie-break strategy %q", strategy)
+		}
+	}
+}
+
+// WithCacheTTL sets the lifetime of cache entries.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(o *options) error {
+		if ttl <= 0 {
+			return fmt.Errorf("cache ttl must be positive, got %s", ttl)
+		}
+		o.cacheTTL = ttl
+		return nil
+	}
+}
+
+// WithCacheShards splits the in-memory cache into n independently locked shards to reduce lock
+// contention under high concurrency. n must be a power of two; 1 keeps a single lock.
+func WithCacheShards(n int) Option {
+	return func(o *options) error {
+		if n <= 0 || n&(n-1) != 0 {
+			return fmt.Errorf("cache shards must be a positive power of two, got %d", n)
+		}
+		o.cacheShards = n
+		return nil
+	}
+}
+
+// WithProviderTTLs caches results produced by the named providers, such as "google" or "static",
+// for their own TTL, for providers whose data stability or caching policy differs from the rest.
+// It takes precedence over WithPrecisionTTLs. Other results use the precision or cache TTL.
+func WithProviderTTLs(ttls map[string]time.Duration) Option {
+	return func(o *options) error {
+		for provider, ttl := range ttls {
+			if ttl <= 0 {
+				return fmt.Errorf("cache ttl for provider %s must be positive, got %s", provider, ttl)
+			}
+		}
+		o.providerTTLs = ttls
+		return nil
+	}
+}
+
+// Precision values reported by Google in a result's geometry.location_type.
+const (
+	PrecisionRooftop           = "ROOFTOP"
+	PrecisionRangeInterpolated = "RANGE_INTERPOLATED"
+	PrecisionGeometricCenter   = "GEOMETRIC_CENTER"
+	PrecisionApproximate       = "APPROXIMATE"
+)
+
+// WithPrecisionTTLs caches results of the given precisions for their own TTL, so precise results
+// can be kept longer than approximate ones. Other results use the cache TTL.
+func WithPrecisionTTLs(ttls map[string]time.Duration) Option {
+	return func(o *options) error {
+		for precision, ttl := range ttls {
+			switch precision {
+			case PrecisionRooftop, PrecisionRangeInterpolated, PrecisionGeometricCenter, PrecisionApproximate:
+			default:
+				return fmt.Errorf("unknown precision %q", precision)
+			}
+			if ttl <= 0 {
+				return fmt.Errorf("cache ttl for %s must be positive, got %s", precision, ttl)
+			}
+		}
+		o.precisionTTLs = ttls
+		return nil
+	}
+}
+
+// WithClock replaces the time source used for cache expiry, so tests can advance time without
+// sleeping. It defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) error {
+		if now == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		o.now = now
+		return nil
+	}
+}
+
+// WithStatsWindow sets the sliding window used for the cache hit ratio.
+func WithStatsWindow(window time.Duration) Option {
+	return func(o *options) error {
+		o.statsWindow = window
+		return nil
+	}
+}
+
+// WithFailureTTLs configures how long failed lookups are remembered. A zero duration disables
+// negative caching for that kind of failure.
+func WithFailureTTLs(failureTTL, noResultsTTL time.Duration) Option {
+	return func(o *options) error {
+		o.failureTTL = failureTTL
+		o.noResultsTTL = noResultsTTL
+		return nil
+	}
+}
+
+// WithMaxLookupDuration caps how long a single lookup, including every provider in the chain, may
+// run regardless of the caller's deadline. Zero removes the ceiling.
+func WithMaxLookupDuration(d time.Duration) Option {
+	return func(o *options) error {
+		o.maxLookup = d
+		return nil
+	}
+}
+
+// WithProviderTimeout bounds each provider attempt so a slow provider cannot consume the whole
+// lookup budget before the next one in the chain is tried. Zero disables the per-provider bound.
+func WithProviderTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("provider timeout must not be negative, got %s", d)
+		}
+		o.providerTimeout = d
+		return nil
+	}
+}
+
+// WithMaxProviderAttempts caps how many providers of the chain are tried for a single lookup.
+// Zero tries every provider.
+func WithMaxProviderAttempts(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("max provider attempts must not be negative, got %d", n)
+		}
+		o.maxProviders = n
+		return nil
+	}
+}
+
+// WithCollisionGuard makes cache hits check that the cached result was looked up for an address
+// with the same letters and digits as the incoming one, treating the hit as a miss otherwise. It
+// protects against normalization merging distinct addresses at the cost of some cache efficiency.
+func WithCollisionGuard(enabled bool) Option {
+	return func(o *options) error {
+		o.collisionGuard = enabled
+		return nil
+	}
+}
+
+// WithMaxInflightLookups caps concurrent upstream lookups. Beyond the cap, lookups that miss the
+// cache fail immediately with ErrOverloaded. Cache hits and callers joining an identical lookup
+// already in flight are never limited. Zero removes the cap.
+func WithMaxInflightLookups(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("max in-flight lookups must not be negative, got %d", n)
+		}
+		o.maxInflight = n
+		return nil
+	}
+}
+
+// WithDefaultCountry appends the first of names to addresses that do not already appear to name a
+// country; every name, such as "Brazil", "Brasil" and "BR", counts as already present. The cache
+// key includes the appended country.
+func WithDefaultCountry(names ...string) Option {
+	return func(o *options) error {
+		o.defaultCountry = names
+		return nil
+	}
+}
+
+// WithDailyCap limits the requests made to Google per day to limit, after which only cached results
+// are served and lookups fail with ErrQuotaCapReached. Days start at resetAt past midnight UTC.
+// A zero limit only counts requests.
+func WithDailyCap(limit int, resetAt time.Duration) Option {
+	return func(o *options) error {
+		if limit < 0 {
+			return fmt.Errorf("daily cap must not be negative, got %d", limit)
+		}
+		if resetAt < 0 || resetAt >= 24*time.Hour {
+			return fmt.Errorf("daily cap reset must be within a day, got %s", resetAt)
+		}
+		o.dailyCap = limit
+		o.dailyCapReset = resetAt
+		return nil
+	}
+}
+
+// WithErrorObserver calls observe with every error returned by an upstream call, for example to
+// raise alerts based on ErrorCategory. observe runs on the request path and must not block.
+func WithErrorObserver(observe func(error)) Option {
+	return func(o *options) error {
+		if observe == nil {
+			return fmt.Errorf("error observer must not be nil")
+		}
+		o.errorObserver = observe
+		return nil
+	}
+}
+
+// WithMetrics reports cache hits and misses and the duration of every provider call to sink.
+func WithMetrics(sink metrics.Sink) Option {
+	return func(o *options) error {
+		if sink == nil {
+			sink = metrics.Nop{}
+		}
+		o.metrics = sink
+		return nil
+	}
+}
+
+// WithFilter installs the address filter applied before any lookup.
+func WithFilter(filter *Filter) Option {
+	return func(o *options) error {
+		o.filter = filter
+		return nil
+	}
+}
+
+// WithCoordinateInputMode selects how coordinate-like addresses are handled.
+func WithCoordinateInputMode(mode string) Option {
+	return func(o *options) error {
+		switch mode {
+		case CoordinateInputAllow, CoordinateInputReject, CoordinateInputFallback:
+			o.coordinateMode = mode
+			return nil
+		default:
+			return fmt.Errorf("unknown coordinate input mode %q", mode)
+		}
+	}
+}
+
+// WithMinAddressLength rejects normalized addresses shorter than n characters with
+// ErrAddressTooShort, without calling any provider. Zero disables the check, for deployments where
+// very short inputs are legitimate.
+func WithMinAddressLength(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("minimum address length must not be negative, got %d", n)
+		}
+		o.minAddressLength = n
+		return nil
+	}
+}
+
+// WithEnrichers appends enrichers to the chain. Enrichers run in the order they were added.
+func WithEnrichers(enrichers ...ResultEnricher) Option {
+	return func(o *options) error {
+		o.enrichers = append(o.enrichers, enrichers...)
+		return nil
+	}
+}
+
+// WithProviders places providers ahead of the built-in Google provider in the chain. Providers are
+// tried in order until one succeeds.
+func WithProviders(providers ...Provider) Option {
+	return func(o *options) error {
+		o.providers = append(o.providers, providers...)
+		return nil
+	}
+}
+
+// WithProviderDecorator wraps every provider of the chain, including the built-in Google provider,
+// with decorate. Decorators are applied in the order they were added.
+func WithProviderDecorator(decorate func(Provider) Provider) Option {
+	return func(o *options) error {
+		o.decorators = append(o.decorators, decorate)
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+package geocode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewDefaults(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if s.memory.ttl != DefaultCacheTTL || len(s.memory.shards) != DefaultCacheShards {
+		t.Errorf("cache ttl %s with %d shards, want %s with %d", s.memory.ttl, len(s.memory.shards), DefaultCacheTTL, DefaultCacheShards)
+	}
+	if s.maxLookup != DefaultMaxLookupDuration || s.minAddressLength != DefaultMinAddressLength {
+		t.Errorf("max lookup %s and min address length %d, want %s and %d", s.maxLookup, s.minAddressLength, DefaultMaxLookupDuration, DefaultMinAddressLength)
+	}
+	if s.coordinateMode != CoordinateInputAllow || s.google.tieBreak != TieBreakFirst {
+		t.Errorf("coordinate mode %q and tie-break %q, want %q and %q", s.coordinateMode, s.google.tieBreak, CoordinateInputAllow, TieBreakFirst)
+	}
+	if len(s.providers) != 1 || s.providers[0] != s.google || s.google.apiKey != "" {
+		t.Errorf("providers = %v, want only the google provider without a key", s.providers)
+	}
+}
+
+func TestNewAppliesOptions(t *testing.T) {
+	static := &stubProvider{name: "static"}
+	s, err := New(
+		WithAPIKey("key"),
+		WithChannel("batch"),
+		WithCacheTTL(time.Hour),
+		WithCacheShards(4),
+		WithMaxLookupDuration(time.Second),
+		WithMinAddressLength(5),
+		WithTieBreak(TieBreakAddress),
+		WithProviders(static),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if s.google.apiKey != "key" || s.google.channel != "batch" {
+		t.Errorf("google key %q and channel %q, want key and batch", s.google.apiKey, s.google.channel)
+	}
+	if s.memory.ttl != time.Hour || len(s.memory.shards) != 4 {
+		t.Errorf("cache ttl %s with %d shards, want 1h with 4", s.memory.ttl, len(s.memory.shards))
+	}
+	if s.maxLookup != time.Second || s.minAddressLength != 5 || s.google.tieBreak != TieBreakAddress {
+		t.Errorf("max lookup %s, min address length %d, tie-break %q", s.maxLookup, s.minAddressLength, s.google.tieBreak)
+	}
+	if len(s.providers) != 2 || s.providers[0] != Provider(static) || s.upstream != Provider(s.google) {
+		t.Errorf("providers = %v, want static then google", s.providers)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "zero cache ttl", opt: WithCacheTTL(0)},
+		{name: "cache shards not a power of two", opt: WithCacheShards(3)},
+		{name: "unknown tie-break", opt: WithTieBreak("random")},
+		{name: "nil clock", opt: WithClock(nil)},
+		{name: "negative provider timeout", opt: WithProviderTimeout(-time.Second)},
+		{name: "negative min address length", opt: WithMinAddressLength(-1)},
+		{name: "unknown coordinate mode", opt: WithCoordinateInputMode("guess")},
+		{name: "invalid signing secret", opt: WithPremiumCredentials("gme-client", "not base64!")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(WithAPIKey("key"), tt.opt); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewServiceKeepsItsBehavior(t *testing.T) {
+	s := NewService("key", -time.Minute)
+	if s.google.apiKey != "key" || s.memory.ttl != -time.Minute {
+		t.Errorf("key %q with cache ttl %s, want key with -1m", s.google.apiKey, s.memory.ttl)
+	}
+	if s.minAddressLength != 0 {
+		t.Errorf("min address length = %d, want 0", s.minAddressLength)
+	}
+	if s.maxLookup != DefaultMaxLookupDuration {
+		t.Errorf("max lookup = %s, want the default", s.maxLookup)
+	}
+}
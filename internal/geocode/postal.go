@@ -0,0 +1,58 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrPostalCodeRequired is returned when a postal code lookup has no postal code.
+	ErrPostalCodeRequired = errors.New("postal code is required")
+	// ErrCountryRequired is returned when a postal code lookup has no country.
+	ErrCountryRequired = errors.New("country is required for postal code lookups")
+	// ErrInvalidComponent is returned when a component value contains reserved characters.
+	ErrInvalidComponent = errors.New("postal code and country must not contain ':' or '|'")
+)
+
+// postalCacheKeyPrefix namespaces postal code lookups so they never collide with free-text
+// addresses in the cache.
+const postalCacheKeyPrefix = "postal:"
+
+// GeocodePostalCode resolves the centroid of a postal code within a country. Free-text geocoding
+// of bare postal codes is unreliable, so the lookup uses Google's components filter instead, and
+// only providers supporting filters are tried. The postal code and country are checked against the
+// address filter as "postal code, country".
+func (s *Service) GeocodePostalCode(ctx context.Context, postalCode, country string) (Result, error) {
+	if err := s.filter.Load().Check(normalizeAddress(postalCode) + ", " + normalizeAddress(country)); err != nil {
+		return Result{}, err
+	}
+
+	components, err := postalComponents(postalCode, country)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return s.resolve(ctx, postalCacheKeyPrefix+components, "", func(ctx context.Context) (Result, error) {
+		return s.lookup(ctx, query{components: components})
+	})
+}
+
+// postalComponents builds the Google components filter for a postal code and country, normalizing
+// both values so equivalent inputs share a cache entry.
+func postalComponents(postalCode, country string) (string, error) {
+	postalCode = strings.ToUpper(strings.TrimSpace(postalCode))
+	country = strings.ToUpper(strings.TrimSpace(country))
+
+	if postalCode == "" {
+		return "", ErrPostalCodeRequired
+	}
+	if country == "" {
+		return "", ErrCountryRequired
+	}
+	if strings.ContainsAny(postalCode+country, ":|") {
+		return "", ErrInvalidComponent
+	}
+
+	return "postal_code:" + postalCode + "|country:" + country, nil
+}
@@ -0,0 +1,102 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestPostalComponents(t *testing.T) {
+	tests := []struct {
+		postalCode, country string
+		want                string
+		wantErr             error
+	}{
+		{postalCode: "01001-000", country: "BR", want: "postal_code:01001-000|country:BR"},
+		{postalCode: " sw1a 1aa ", country: "gb", want: "postal_code:SW1A 1AA|country:GB"},
+		{postalCode: "", country: "BR", wantErr: ErrPostalCodeRequired},
+		{postalCode: "01001-000", country: " ", wantErr: ErrCountryRequired},
+		{postalCode: "01001|000", country: "BR", wantErr: ErrInvalidComponent},
+		{postalCode: "01001-000", country: "country:BR", wantErr: ErrInvalidComponent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.postalCode+"/"+tt.country, func(t *testing.T) {
+			got, err := postalComponents(tt.postalCode, tt.country)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("postalComponents = %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeocodePostalCodeQueriesComponentsAndCachesPerPair(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query().Get("components")+" address="+r.URL.Query().Get("address"))
+		mu.Unlock()
+		respond(http.StatusOK, sePayload)(w, r)
+	})
+	s := newTestService(t, google.option())
+
+	lookups := []struct{ postalCode, country string }{
+		{"01001-000", "BR"},
+		{"01001-000", "br"},
+		{"01001-000", "PT"},
+		{" 01001-000 ", "BR"},
+	}
+	for _, l := range lookups {
+		if _, err := s.GeocodePostalCode(context.Background(), l.postalCode, l.country); err != nil {
+			t.Fatalf("GeocodePostalCode(%q, %q): %v", l.postalCode, l.country, err)
+		}
+	}
+
+	want := []string{
+		"postal_code:01001-000|country:BR address=",
+		"postal_code:01001-000|country:PT address=",
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("upstream queries = %q, want %q", queries, want)
+	}
+	for i := range want {
+		if queries[i] != want[i] {
+			t.Errorf("query %d = %q, want %q", i, queries[i], want[i])
+		}
+	}
+
+	// Postal lookups are cached apart from free-text addresses.
+	if _, ok := s.cache.Get(postalCacheKeyPrefix + "postal_code:01001-000|country:BR"); !ok {
+		t.Error("postal lookup was not cached under its own key")
+	}
+}
+
+func TestGeocodePostalCodeBlocked(t *testing.T) {
+	filter, err := NewFilter([]string{"99999-000", "re:, kp$"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	tests := []struct {
+		postalCode, country string
+		wantErr             error
+	}{
+		{postalCode: "99999-000", country: "BR", wantErr: ErrAddressBlocked},
+		{postalCode: "01001-000", country: "kp", wantErr: ErrAddressBlocked},
+		{postalCode: "01001-000", country: "BR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.postalCode+"/"+tt.country, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+			s := newTestService(t, google.option(), WithFilter(filter))
+
+			if _, err := s.GeocodePostalCode(context.Background(), tt.postalCode, tt.country); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GeocodePostalCode = %v, want %v", err, tt.wantErr)
+			}
+			if called := google.requests.Load() > 0; called != (tt.wantErr == nil) {
+				t.Errorf("Google called = %v, want %v", called, tt.wantErr == nil)
+			}
+		})
+	}
+}
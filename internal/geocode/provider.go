@@ -0,0 +1,347 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider resolves a normalized address into a geocoding result. Providers return ErrNoResults
+// when they have no answer so the next provider in the chain can be tried.
+type Provider interface {
+	Name() string
+	Geocode(ctx context.Context, address string) (Result, error)
+}
+
+// FilteredGeocoder is implemented by providers that can restrict a lookup with a components filter
+// such as "postal_code:01001-000|country:BR". Lookups with a filter skip providers without it.
+type FilteredGeocoder interface {
+	GeocodeFiltered(ctx context.Context, address, components string) (Result, error)
+}
+
+// TimeZoner is implemented by providers that resolve the time zone of a coordinate.
+type TimeZoner interface {
+	TimeZone(ctx context.Context, lat, lng float64, at time.Time) (TimeZone, error)
+}
+
+// query is what the provider chain is asked to resolve: a free-text address, a components filter,
+// or both.
+type query struct {
+	address    string
+	components string
+}
+
+// supports reports whether provider can answer q. Decorators are looked through, since they
+// forward every method whether or not the provider they wrap has it.
+func (q query) supports(provider Provider) bool {
+	if q.components == "" {
+		return true
+	}
+	_, ok := unwrap(provider).(FilteredGeocoder)
+	return ok
+}
+
+// geocode asks provider to resolve q.
+func (q query) geocode(ctx context.Context, provider Provider) (Result, error) {
+	if q.components == "" {
+		return provider.Geocode(ctx, q.address)
+	}
+	filtered, ok := provider.(FilteredGeocoder)
+	if !ok {
+		return Result{}, ErrNoResults
+	}
+	return filtered.GeocodeFiltered(ctx, q.address, q.components)
+}
+
+// unwrap returns the provider behind any decorators, which report the provider they wrap with
+// Unwrap.
+func unwrap(provider Provider) Provider {
+	for {
+		wrapper, ok := provider.(interface{ Unwrap() Provider })
+		if !ok {
+			return provider
+		}
+		provider = wrapper.Unwrap()
+	}
+}
+
+// ErrProviderTimeout is returned for a provider that did not answer within its attempt timeout.
+var ErrProviderTimeout = errors.New("provider did not answer within its attempt timeout")
+
+// ProviderFailure is the error returned by one provider in the chain.
+type ProviderFailure struct {
+	Provider string
+	Err      error
+}
+
+// ChainError lists the failure of every provider tried, in order. It unwraps to the error of the
+// last provider so callers classify it the same way as a single provider's error.
+type ChainError struct {
+	Failures []ProviderFailure
+}
+
+func (e *ChainError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		parts[i] = failure.Provider + ": " + failure.Err.Error()
+	}
+	return "all providers failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ChainError) Unwrap() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e.Failures[len(e.Failures)-1].Err
+}
+
+// lookup walks the provider chain and returns the first successful result. Each provider gets at
+// most the configured attempt timeout, and at most the configured number of providers is tried.
+// Providers that cannot apply the query's components filter are skipped. When every attempt fails,
+// a *ChainError lists them all. The chain stops as soon as ctx is done so a disconnected client
+// never triggers further upstream calls.
+func (s *Service) lookup(ctx context.Context, q query) (Result, error) {
+	providers := s.providers
+	if s.maxProviders > 0 && len(providers) > s.maxProviders {
+		providers = providers[:s.maxProviders]
+	}
+
+	var failures []ProviderFailure
+	for _, provider := range providers {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Result{}, ctxErr
+		}
+		if !q.supports(provider) {
+			continue
+		}
+		var result Result
+		err := s.attempt(ctx, provider, func(ctx context.Context) (err error) {
+			result, err = q.geocode(ctx, provider)
+			return err
+		})
+		TraceFromContext(ctx).attempted(provider.Name(), err)
+		if err == nil {
+			if len(failures) > 0 {
+				TraceFromContext(ctx).fellBack(retries(failures))
+			}
+			return result, nil
+		}
+		s.observeError(err)
+		failures = append(failures, ProviderFailure{Provider: provider.Name(), Err: err})
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Result{}, ctxErr
+	}
+	if len(failures) == 0 {
+		return Result{}, ErrNoResults
+	}
+	return Result{}, &ChainError{Failures: failures}
+}
+
+// retries counts the failures that were actual errors. A provider answering ErrNoResults simply
+// does not know the address, which is routine for the static dataset, so it is not a retry.
+func retries(failures []ProviderFailure) int {
+	n := 0
+	for _, failure := range failures {
+		if !errors.Is(failure.Err, ErrNoResults) {
+			n++
+		}
+	}
+	return n
+}
+
+// outcome classifies a provider error for metric tags.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNoResults):
+		return "no_results"
+	case errors.Is(err, ErrProviderTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// errorCategory classifies an upstream error for metric tags, folding the errors ErrorCategory
+// leaves uncategorized into "other".
+func errorCategory(err error) string {
+	if category := ErrorCategory(err); category != "" {
+		return category
+	}
+	return "other"
+}
+
+// attempt makes a single call to provider under the per-provider timeout, if any, and records its
+// duration, its outcome in the provider's health and, for Google, in the denied streak. Calls that
+// fail count as "upstream.errors" tagged with the error category, unless the provider simply did
+// not know the address or the caller went away.
+func (s *Service) attempt(ctx context.Context, provider Provider, call func(context.Context) error) error {
+	start := time.Now()
+	err := s.withProviderTimeout(ctx, call)
+	s.metrics.Timing("upstream.duration", time.Since(start), "provider:"+provider.Name(), "outcome:"+outcome(err))
+	if err != nil && !errors.Is(err, ErrNoResults) && !errors.Is(err, context.Canceled) {
+		s.metrics.Count("upstream.errors", 1, "provider:"+provider.Name(), "category:"+errorCategory(err))
+	}
+	s.health.record(provider.Name(), err)
+	if provider.Name() == s.google.Name() {
+		s.denials.record(err)
+	}
+	return err
+}
+
+func (s *Service) withProviderTimeout(ctx context.Context, call func(context.Context) error) error {
+	if s.providerTimeout <= 0 {
+		return call(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	err := call(attemptCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return ErrProviderTimeout
+	}
+	return err
+}
+
+// ProviderStatus describes a provider in the chain and the outcome of its most recent call.
+type ProviderStatus struct {
+	Name                string    `json:"name"`
+	Default             bool      `json:"default"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastCall            time.Time `json:"last_call"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// ProviderStatuses reports every provider in the chain, in order. Providers that have not been
+// called yet are reported as healthy. The built-in Google provider, always last, is the default.
+func (s *Service) ProviderStatuses() []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(s.providers))
+	for i, provider := range s.providers {
+		status := s.health.status(provider.Name())
+		status.Default = i == len(s.providers)-1
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// providerHealth tracks the outcome of the last call made to each provider.
+type providerHealth struct {
+	mu       sync.Mutex
+	statuses map[string]ProviderStatus
+}
+
+func newProviderHealth() *providerHealth {
+	return &providerHealth{statuses: make(map[string]ProviderStatus)}
+}
+
+// record stores the outcome of a call. ErrNoResults means the provider answered, and context
+// errors describe the caller, so neither counts as a failure.
+func (h *providerHealth) record(name string, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := h.statuses[name]
+	status.Name = name
+	status.LastCall = time.Now()
+	if err == nil || errors.Is(err, ErrNoResults) {
+		status.Healthy = true
+		status.ConsecutiveFailures = 0
+		status.LastError = ""
+	} else {
+		status.Healthy = false
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+	}
+	h.statuses[name] = status
+}
+
+func (h *providerHealth) status(name string) ProviderStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status, ok := h.statuses[name]
+	if !ok {
+		return ProviderStatus{Name: name, Healthy: true}
+	}
+	return status
+}
+
+// StaticProvider answers from a fixed dataset of known addresses without any external call.
+type StaticProvider struct {
+	entries map[string]Result
+}
+
+// StaticEntry is a single known location in a static dataset file.
+type StaticEntry struct {
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// NewStaticProvider indexes entries by their normalized address, matching the cache keys.
+func NewStaticProvider(entries []StaticEntry) *StaticProvider {
+	p := &StaticProvider{entries: make(map[string]Result, len(entries))}
+	for _, entry := range entries {
+		key := normalizeAddress(entry.Address)
+		if key == "" {
+			continue
+		}
+		p.entries[key] = Result{
+			Address:   entry.Address,
+			Latitude:  entry.Latitude,
+			Longitude: entry.Longitude,
+			Source:    p.Name(),
+		}
+	}
+	return p
+}
+
+// rekeyed returns a copy of p with every key passed through key, leaving p unchanged.
+func (p *StaticProvider) rekeyed(key func(string) string) *StaticProvider {
+	rekeyed := &StaticProvider{entries: make(map[string]Result, len(p.entries))}
+	for k, result := range p.entries {
+		rekeyed.entries[key(k)] = result
+	}
+	return rekeyed
+}
+
+// LoadStaticProvider reads a JSON array of StaticEntry values from path.
+func LoadStaticProvider(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []StaticEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid static dataset: %w", err)
+	}
+	return NewStaticProvider(entries), nil
+}
+
+// Name identifies the provider in results and diagnostics.
+func (p *StaticProvider) Name() string {
+	return "static"
+}
+
+// Geocode returns the known location for address or ErrNoResults.
+func (p *StaticProvider) Geocode(_ context.Context, address string) (Result, error) {
+	if result, ok := p.entries[address]; ok {
+		return result, nil
+	}
+	return Result{}, ErrNoResults
+}
@@ -0,0 +1,214 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStaticProviderResolvesDatasetEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "static.json")
+	dataset := `[
+		{"address": "Praça da Sé, São Paulo", "latitude": -23.5503, "longitude": -46.6339},
+		{"address": "  ", "latitude": 1, "longitude": 1}
+	]`
+	if err := os.WriteFile(path, []byte(dataset), 0o600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+	static, err := LoadStaticProvider(path)
+	if err != nil {
+		t.Fatalf("LoadStaticProvider: %v", err)
+	}
+	fallback := &stubProvider{name: "fallback", answer: answerWith(Result{Latitude: 9, Longitude: 9, Source: "fallback"})}
+	s := newTestService(t, WithProviders(static, fallback))
+
+	tests := []struct {
+		address    string
+		wantSource string
+		wantLat    float64
+	}{
+		{address: "praça da sé, são paulo", wantSource: "static", wantLat: -23.5503},
+		{address: "  PRAÇA DA SÉ, SÃO PAULO ", wantSource: "cache", wantLat: -23.5503},
+		{address: "Avenida Paulista, 1000", wantSource: "fallback", wantLat: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			result, err := s.Geocode(context.Background(), tt.address)
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Source != tt.wantSource || result.Latitude != tt.wantLat {
+				t.Errorf("result = %s at %v, want %s at %v", result.Source, result.Latitude, tt.wantSource, tt.wantLat)
+			}
+		})
+	}
+	if got := fallback.calls.Load(); got != 1 {
+		t.Errorf("fallback calls = %d, want 1", got)
+	}
+}
+
+func TestLoadStaticProviderInvalidDataset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "static.json")
+	if err := os.WriteFile(path, []byte(`{"address": "not an array"}`), 0o600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+	if _, err := LoadStaticProvider(path); err == nil {
+		t.Fatal("expected an error for an invalid dataset")
+	}
+}
+
+func TestProviderStatuses(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	errDown := errors.New("dataset unavailable")
+	flaky := &stubProvider{name: "flaky", answer: func(context.Context, string) (Result, error) {
+		return Result{}, errDown
+	}}
+	s := newTestService(t, google.option(), WithProviders(flaky))
+
+	statuses := s.ProviderStatuses()
+	if len(statuses) != 2 || statuses[0].Name != "flaky" || statuses[1].Name != "google" {
+		t.Fatalf("statuses = %+v, want flaky then google", statuses)
+	}
+	for _, status := range statuses {
+		if !status.Healthy || !status.LastCall.IsZero() {
+			t.Errorf("%s before any call = %+v, want healthy and never called", status.Name, status)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Geocode(context.Background(), fmt.Sprintf("Rua %d, São Paulo", i)); err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+	}
+
+	statuses = s.ProviderStatuses()
+	flakyStatus, googleStatus := statuses[0], statuses[1]
+	if flakyStatus.Healthy || flakyStatus.ConsecutiveFailures != 2 || flakyStatus.LastError != errDown.Error() || flakyStatus.Default {
+		t.Errorf("flaky = %+v, want unhealthy after 2 failures", flakyStatus)
+	}
+	if !googleStatus.Healthy || googleStatus.ConsecutiveFailures != 0 || googleStatus.LastCall.IsZero() || !googleStatus.Default {
+		t.Errorf("google = %+v, want the healthy default provider", googleStatus)
+	}
+}
+
+// slowProvider blocks until its context is done.
+func slowProvider(name string) *stubProvider {
+	return &stubProvider{name: name, answer: func(ctx context.Context, _ string) (Result, error) {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}}
+}
+
+func TestProviderTimeoutLeavesTimeForTheNextProvider(t *testing.T) {
+	slow := slowProvider("slow")
+	second := &stubProvider{name: "second", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "second"})}
+	s := newTestService(t, WithProviders(slow, second), WithProviderTimeout(50*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	start := time.Now()
+	result, err := s.Geocode(ctx, "Praça da Sé")
+	if err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Geocode took %s with a 50ms provider timeout", elapsed)
+	}
+	if result.Source != "second" || slow.calls.Load() != 1 || second.calls.Load() != 1 {
+		t.Errorf("result from %s after slow=%d second=%d calls, want second after one call each", result.Source, slow.calls.Load(), second.calls.Load())
+	}
+}
+
+func TestChainErrorListsEveryFailure(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`))
+	errDown := errors.New("dataset unavailable")
+	broken := &stubProvider{name: "broken", answer: func(context.Context, string) (Result, error) { return Result{}, errDown }}
+
+	tests := []struct {
+		name         string
+		maxProviders int
+		want         []ProviderFailure
+		wantIs       error
+	}{
+		{
+			name: "whole chain",
+			want: []ProviderFailure{
+				{Provider: "slow", Err: ErrProviderTimeout},
+				{Provider: "broken", Err: errDown},
+				{Provider: "google", Err: ErrNoResults},
+			},
+			wantIs: ErrNoResults,
+		},
+		{
+			name:         "capped attempts",
+			maxProviders: 2,
+			want: []ProviderFailure{
+				{Provider: "slow", Err: ErrProviderTimeout},
+				{Provider: "broken", Err: errDown},
+			},
+			wantIs: errDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, google.option(),
+				WithProviders(slowProvider("slow"), broken),
+				WithProviderTimeout(20*time.Millisecond),
+				WithMaxProviderAttempts(tt.maxProviders),
+				WithFailureTTLs(0, 0))
+
+			_, err := s.Geocode(context.Background(), "Rua Inexistente, 999")
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				t.Fatalf("Geocode = %v, want a *ChainError", err)
+			}
+			if len(chainErr.Failures) != len(tt.want) {
+				t.Fatalf("failures = %v, want %v", chainErr.Failures, tt.want)
+			}
+			for i, want := range tt.want {
+				if got := chainErr.Failures[i]; got.Provider != want.Provider || !errors.Is(got.Err, want.Err) {
+					t.Errorf("failure %d = %s: %v, want %s: %v", i, got.Provider, got.Err, want.Provider, want.Err)
+				}
+			}
+			if !errors.Is(err, tt.wantIs) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestUpstreamErrorsAreCountedByCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "quota", err: &APIStatusError{API: "google maps api", Status: "OVER_QUERY_LIMIT"}, want: "upstream.errors|provider:stub,category:quota"},
+		{name: "denied", err: &APIStatusError{API: "google maps api", Status: "REQUEST_DENIED"}, want: "upstream.errors|provider:stub,category:denied"},
+		{name: "server error", err: &UpstreamStatusError{API: "google maps api", StatusCode: http.StatusBadGateway}, want: "upstream.errors|provider:stub,category:upstream_5xx"},
+		{name: "uncategorized", err: errors.New("connection reset"), want: "upstream.errors|provider:stub,category:other"},
+		{name: "no results", err: ErrNoResults},
+		{name: "success"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &countingSink{}
+			provider := &stubProvider{name: "stub", answer: func(context.Context, string) (Result, error) { return Result{Source: "stub"}, tt.err }}
+			google := newFakeGoogle(t, respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`))
+			s := newTestService(t, google.option(), WithMetrics(sink), WithProviders(provider))
+			s.Geocode(context.Background(), "Praça da Sé")
+
+			if got := sink.get("upstream.errors"); (tt.want == "") != (got == 0) {
+				t.Errorf("upstream.errors = %d, want counted %v", got, tt.want != "")
+			}
+			if tt.want != "" && sink.get(tt.want) != 1 {
+				t.Errorf("%s not counted once: %v", tt.want, sink.counts)
+			}
+		})
+	}
+}
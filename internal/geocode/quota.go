@@ -0,0 +1,79 @@
+package geocode
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQuotaCapReached is returned instead of calling Google once the daily cap has been used up.
+// Cached results are still served until the cap resets.
+var ErrQuotaCapReached = errors.New("daily upstream request cap reached")
+
+// QuotaStatus reports the upstream requests made in the current daily period.
+type QuotaStatus struct {
+	// Limit is the daily cap, or zero when requests are unlimited.
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining,omitempty"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// dailyQuota counts billable Google requests per day. A day starts at resetOffset past midnight UTC.
+type dailyQuota struct {
+	limit       int
+	resetOffset time.Duration
+	now         func() time.Time
+
+	mu     sync.Mutex
+	period time.Time
+	used   int
+}
+
+func newDailyQuota(limit int, resetOffset time.Duration, now func() time.Time) *dailyQuota {
+	return &dailyQuota{limit: limit, resetOffset: resetOffset, now: now}
+}
+
+// take records one request, or returns ErrQuotaCapReached when the cap is used up. A nil quota
+// allows everything.
+func (q *dailyQuota) take() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.roll()
+	if q.limit > 0 && q.used >= q.limit {
+		return ErrQuotaCapReached
+	}
+	q.used++
+	return nil
+}
+
+func (q *dailyQuota) status() QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.roll()
+	status := QuotaStatus{Limit: q.limit, Used: q.used, ResetsAt: q.period.Add(24 * time.Hour)}
+	if q.limit > 0 {
+		remaining := max(q.limit-q.used, 0)
+		status.Remaining = &remaining
+	}
+	return status
+}
+
+// roll starts a new period when the current one is over. The caller must hold q.mu.
+func (q *dailyQuota) roll() {
+	period := q.now().UTC().Add(-q.resetOffset).Truncate(24 * time.Hour).Add(q.resetOffset)
+	if !period.Equal(q.period) {
+		q.period = period
+		q.used = 0
+	}
+}
+
+// QuotaStatus reports the Google requests made in the current daily period against the cap.
+func (s *Service) QuotaStatus() QuotaStatus {
+	return s.google.quota.status()
+}
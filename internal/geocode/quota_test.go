@@ -0,0 +1,61 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDailyCap(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	clock := newFakeClock() // 2024-03-01 12:00 UTC
+	s := newTestService(t, google.option(), WithClock(clock.Now), WithDailyCap(2, 3*time.Hour), WithCacheTTL(72*time.Hour))
+	resetsAt := time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name         string
+		advance      time.Duration
+		address      string
+		wantErr      error
+		wantRequests int32
+		wantUsed     int
+		wantResetsAt time.Time
+	}{
+		{name: "first", address: "Rua 1", wantRequests: 1, wantUsed: 1, wantResetsAt: resetsAt},
+		{name: "second", address: "Rua 2", wantRequests: 2, wantUsed: 2, wantResetsAt: resetsAt},
+		{name: "cap reached", address: "Rua 3", wantErr: ErrQuotaCapReached, wantRequests: 2, wantUsed: 2, wantResetsAt: resetsAt},
+		{name: "cached while capped", address: "Rua 1", wantRequests: 2, wantUsed: 2, wantResetsAt: resetsAt},
+		{name: "just before the reset", advance: 15*time.Hour - time.Second, address: "Rua 4", wantErr: ErrQuotaCapReached, wantRequests: 2, wantUsed: 2, wantResetsAt: resetsAt},
+		{name: "at the reset", advance: time.Second, address: "Rua 5", wantRequests: 3, wantUsed: 1, wantResetsAt: resetsAt.Add(24 * time.Hour)},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		_, err := s.Geocode(context.Background(), step.address)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: Geocode = %v, want %v", step.name, err, step.wantErr)
+		}
+		if got := google.requests.Load(); got != step.wantRequests {
+			t.Errorf("%s: upstream requests = %d, want %d", step.name, got, step.wantRequests)
+		}
+		status := s.QuotaStatus()
+		if status.Limit != 2 || status.Used != step.wantUsed || status.Remaining == nil || *status.Remaining != 2-step.wantUsed || !status.ResetsAt.Equal(step.wantResetsAt) {
+			t.Errorf("%s: status = %+v, want %d used until %s", step.name, status, step.wantUsed, step.wantResetsAt)
+		}
+	}
+}
+
+func TestDailyCapDisabled(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	s := newTestService(t, google.option())
+
+	for _, address := range []string{"Rua 1", "Rua 2", "Rua 3"} {
+		if _, err := s.Geocode(context.Background(), address); err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+	}
+	if status := s.QuotaStatus(); status.Limit != 0 || status.Used != 3 || status.Remaining != nil {
+		t.Errorf("status = %+v, want 3 used without a limit", status)
+	}
+}
@@ -0,0 +1,95 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultReadinessTimeout bounds all readiness checks together when no timeout is configured.
+const DefaultReadinessTimeout = time.Second
+
+// googleHealthURL is probed to confirm the Google Maps API host is reachable. It does not consume
+// geocoding quota.
+const googleHealthURL = "https://maps.googleapis.com/"
+
+// Pinger is implemented by providers that can cheaply verify their upstream is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingerOf returns the Pinger behind provider, looking through decorators such as ChaosProvider
+// and AdaptiveProvider.
+func pingerOf(provider Provider) (Pinger, bool) {
+	pinger, ok := unwrap(provider).(Pinger)
+	return pinger, ok
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CheckReadiness pings every provider that implements Pinger, directly or behind decorators,
+// concurrently. All checks share a
+// single deadline of timeout, independent of any request timeout, and a check that has not
+// finished by then is reported as unhealthy without waiting for it.
+func (s *Service) CheckReadiness(ctx context.Context, timeout time.Duration) ([]CheckResult, bool) {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		index int
+		err   error
+	}
+
+	var results []CheckResult
+	outcomes := make(chan outcome, len(s.providers))
+	for _, provider := range s.providers {
+		pinger, ok := pingerOf(provider)
+		if !ok {
+			continue
+		}
+		index := len(results)
+		results = append(results, CheckResult{Name: provider.Name(), Error: "check timed out"})
+		go func() {
+			outcomes <- outcome{index: index, err: pinger.Ping(ctx)}
+		}()
+	}
+
+	ready := true
+	for pending := len(results); pending > 0; pending-- {
+		select {
+		case o := <-outcomes:
+			if o.err != nil {
+				results[o.index].Error = o.err.Error()
+				ready = false
+				continue
+			}
+			results[o.index].Healthy = true
+			results[o.index].Error = ""
+		case <-ctx.Done():
+			return results, false
+		}
+	}
+	return results, ready
+}
+
+// Ping checks that the Google Maps API host answers HTTP requests.
+func (p *GoogleProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, googleHealthURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.do(req)
+	if err != nil {
+		return err
+	}
+	closeBody(resp.Body)
+	return nil
+}
@@ -0,0 +1,63 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// pingingProvider is a stubProvider whose readiness check is ping.
+type pingingProvider struct {
+	stubProvider
+	ping func(ctx context.Context) error
+}
+
+func (p *pingingProvider) Ping(ctx context.Context) error {
+	return p.ping(ctx)
+}
+
+func TestCheckReadiness(t *testing.T) {
+	blocked := make(chan struct{})
+	t.Cleanup(func() { close(blocked) })
+
+	tests := []struct {
+		name       string
+		ping       func(ctx context.Context) error
+		wantReady  bool
+		wantHealth bool
+		wantError  string
+	}{
+		{name: "healthy", ping: func(context.Context) error { return nil }, wantReady: true, wantHealth: true},
+		{name: "failing", ping: func(context.Context) error { return errors.New("connection refused") }, wantError: "connection refused"},
+		{
+			name: "blocking past the timeout",
+			// Ignores its context on purpose, like a dependency stuck in a call without a deadline.
+			ping:      func(context.Context) error { <-blocked; return nil },
+			wantError: "check timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, ""))
+			dependency := &pingingProvider{stubProvider: stubProvider{name: "dependency"}, ping: tt.ping}
+			s := newTestService(t, google.option(), WithProviders(dependency))
+
+			start := time.Now()
+			checks, ready := s.CheckReadiness(context.Background(), 50*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("CheckReadiness took %s with a 50ms timeout", elapsed)
+			}
+			if ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v", ready, tt.wantReady)
+			}
+			if len(checks) != 2 || checks[0].Name != "dependency" || checks[1].Name != "google" {
+				t.Fatalf("checks = %+v, want dependency then google", checks)
+			}
+			if checks[0].Healthy != tt.wantHealth || checks[0].Error != tt.wantError {
+				t.Errorf("dependency check = %+v, want healthy %v with error %q", checks[0], tt.wantHealth, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package geocode
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redactedParams are the query parameters that carry credentials in outbound Google URLs.
+var redactedParams = map[string]bool{"key": true, "signature": true}
+
+// RedactURL returns rawURL with the values of credential query parameters (the API key and the
+// premium signature) replaced by "***", so the URL can be logged safely. Parameter order is kept.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "[unparseable url]"
+	}
+	if u.RawQuery == "" {
+		return rawURL
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil && redactedParams[decoded] {
+			pairs[i] = name + "=***"
+		}
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	return u.String()
+}
+
+// do sends req, redacting credentials from the URL embedded in transport errors so they never reach
+// logs or client responses.
+func (p *GoogleProvider) do(req *http.Request) (*http.Response, error) {
+	resp, err := p.client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return resp, err
+}
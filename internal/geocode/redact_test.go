@@ -0,0 +1,66 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "api key",
+			url:  "https://maps.googleapis.com/maps/api/geocode/json?address=Pra%C3%A7a+da+S%C3%A9&key=AIzaSyD-secret",
+			want: "https://maps.googleapis.com/maps/api/geocode/json?address=Pra%C3%A7a+da+S%C3%A9&key=***",
+		},
+		{
+			name: "premium signature",
+			url:  "https://maps.googleapis.com/maps/api/geocode/json?address=S%C3%A9&client=gme-client&signature=chaRF2hTJKOScPr-RQCEhZbSzIE=",
+			want: "https://maps.googleapis.com/maps/api/geocode/json?address=S%C3%A9&client=gme-client&signature=***",
+		},
+		{
+			name: "escaped parameter name",
+			url:  "https://maps.googleapis.com/maps/api/geocode/json?k%65y=AIzaSyD-secret&address=S%C3%A9",
+			want: "https://maps.googleapis.com/maps/api/geocode/json?k%65y=***&address=S%C3%A9",
+		},
+		{
+			name: "no credentials",
+			url:  "https://maps.googleapis.com/maps/api/geocode/json?address=S%C3%A9",
+			want: "https://maps.googleapis.com/maps/api/geocode/json?address=S%C3%A9",
+		},
+		{name: "unparseable", url: "https://[::1", want: "[unparseable url]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactURL(tt.url); got != tt.want {
+				t.Errorf("RedactURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportErrorsDoNotLeakTheKey(t *testing.T) {
+	failing := WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset by peer")
+		})
+	})
+	s, err := New(WithAPIKey("AIzaSyD-secret"), failing, WithFailureTTLs(0, 0))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = s.Geocode(context.Background(), "Praça da Sé")
+	if err == nil {
+		t.Fatal("expected a transport error")
+	}
+	if msg := err.Error(); strings.Contains(msg, "AIzaSyD-secret") || !strings.Contains(msg, "key=***") {
+		t.Errorf("error = %q, want the key redacted", msg)
+	}
+}
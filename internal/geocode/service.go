@@ -2,20 +2,28 @@ package geocode
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"apigo/internal/metrics"
 )
 
+// WarningNoCountry is reported when the provider could not determine the result's country,
+// which is common for vague inputs.
+const WarningNoCountry = "no_country"
+
 var (
 	// ErrAddressRequired is returned when no address is provided.
 	ErrAddressRequired = errors.New("address is required")
-	// ErrNoResults is returned when the Google API finds no results for the address.
+	// ErrAddressTooShort is returned when the normalized address is shorter than the configured
+	// minimum length. No provider is called for it.
+	ErrAddressTooShort = errors.New("address is too short")
+	// ErrNoResults is returned when no provider finds results for the address.
 	ErrNoResults = errors.New("no results found")
 )
 
@@ -25,144 +33,352 @@ type Result struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Source    string  `json:"source"`
+
+	// CountryCode is the ISO 3166-1 alpha-2 code of the result's country, when known.
+	CountryCode string `json:"country_code,omitempty"`
+	// PlusCode is the global Open Location Code of the result, when the provider reports one. It is
+	// often the only identifier available for remote locations without a street address.
+	PlusCode string `json:"plus_code,omitempty"`
+	// Components holds the address components of the result, when the provider reports them.
+	Components *Components `json:"components,omitempty"`
+	// PartialMatch is set when the provider matched only part of the address. Precision is the
+	// provider's location type, such as ROOFTOP or APPROXIMATE, when it reports one.
+	PartialMatch bool   `json:"partial_match,omitempty"`
+	Precision    string `json:"precision,omitempty"`
+
+	// Warnings flags quality issues with the result, such as WarningNoCountry.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Query is the address, as sent by the caller, that the result was looked up for. It backs the
+	// collision guard and is not exposed to clients.
+	Query string `json:"query,omitempty"`
+
+	// RetrievedAt is when the result was fetched from a provider and ExpiresAt when its cache entry
+	// expires. Cache hits keep the values of the original lookup.
+	RetrievedAt time.Time `json:"retrieved_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// Extra holds integrator-defined fields attached by a ResultEnricher.
+	Extra map[string]any `json:"extra,omitempty"`
+
+	// Debug holds the raw upstream response when debug capture is enabled. It is never serialized
+	// directly; the HTTP layer decides whether a client may see it.
+	Debug *DebugInfo `json:"-"`
 }
 
-// Service performs geocoding requests against the Google Maps Geocoding API.
+// Components are the parts of a result's address, named after Google's address component types.
+// Each one is only present when the provider reports it. The state is the short form (for example
+// "SP" or "CA"); other components use the full name.
+type Components struct {
+	StreetNumber string `json:"street_number,omitempty"`
+	Route        string `json:"route,omitempty"`
+
+	AdministrativeAreaLevel1 string `json:"administrative_area_level_1,omitempty"`
+	// AdministrativeAreaLevel2 is the county in the US and the municipality in Brazil.
+	AdministrativeAreaLevel2 string `json:"administrative_area_level_2,omitempty"`
+	Locality                 string `json:"locality,omitempty"`
+	Sublocality              string `json:"sublocality,omitempty"`
+	Neighborhood             string `json:"neighborhood,omitempty"`
+
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// Service geocodes addresses through a chain of providers, caching successful results.
 type Service struct {
-	apiKey string
-	client *http.Client
-	cache  *cache
+	google *GoogleProvider
+	// upstream is google wrapped by every decorator; calls to Google go through it.
+	upstream  Provider
+	providers []Provider
+	cache     Cache
+	memory    *cache
+	counters  atomic.Pointer[cacheCounters]
+	canonical *canonicalCache
+	churn     *churnGuard
+	filter    atomic.Pointer[Filter]
+	flight    *flightGroup
+	failures  *failureCache
+	timezones *timezoneCache
+	health    *providerHealth
+	maxLookup time.Duration
+
+	providerTimeout time.Duration
+	maxProviders    int
+	collisionGuard  bool
+	metrics         metrics.Sink
+	inflight        inflightLimit
+	defaultCountry  *defaultCountry
+	observeError    func(error)
+	denials         *deniedStreak
+
+	coordinateMode   string
+	minAddressLength int
+	enrichers        []ResultEnricher
 }
 
-// NewService creates a configured Service instance. cacheTTL determines the lifetime of cache entries.
-func NewService(apiKey string, cacheTTL time.Duration) *Service {
-	return &Service{
-		apiKey: apiKey,
-		client: &http.Client{Timeout: 5 * time.Second},
-		cache:  newCache(cacheTTL),
+// New creates a Service configured by opts.
+func New(opts ...Option) (*Service, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
 	}
+	return newService(o), nil
 }
 
-// Geocode retrieves the coordinates for an address. It will use an in-memory cache before
-// querying the Google Maps API to keep the service responsive under heavy load.
-func (s *Service) Geocode(ctx context.Context, rawAddress string) (Result, error) {
-	address := normalizeAddress(rawAddress)
-	if address == "" {
-		return Result{}, ErrAddressRequired
+// newService builds a Service from validated options.
+func newService(o options) *Service {
+	google := NewGoogleProvider(o.apiKey)
+	google.channel = o.channel
+	google.premium = o.premium
+	google.debugMaxBytes = o.debugMaxBytes
+	google.tieBreak = o.tieBreak
+	google.addressOrder = o.addressOrder
+	var transport http.RoundTripper = newTransport(o.transport)
+	for _, wrap := range o.roundTripper {
+		transport = wrap(transport)
 	}
+	google.client.Transport = transport
+	google.quota = newDailyQuota(o.dailyCap, o.dailyCapReset, o.now)
 
-	if result, ok := s.cache.Get(address); ok {
-		result.Source = "cache"
-		return result, nil
+	defaultCountry := newDefaultCountry(o.defaultCountry)
+	providers := append(append([]Provider(nil), o.providers...), google)
+	for i := range providers {
+		// Lookups use keys with the default country applied, so static datasets must be indexed the
+		// same way for their entries to keep matching.
+		if static, ok := providers[i].(*StaticProvider); ok && defaultCountry != nil {
+			providers[i] = static.rekeyed(defaultCountry.apply)
+		}
+		for _, decorate := range o.decorators {
+			providers[i] = decorate(providers[i])
+		}
 	}
 
-	apiURL := fmt.Sprintf("https://maps.googleapis.com/maps/api/geocode/json?address=%s&key=%s",
-		url.QueryEscape(address), s.apiKey,
-	)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
-	if err != nil {
-		return Result{}, err
+	memory := newCache(o.cacheTTL, o.now, o.cacheShards)
+	memory.providerTTLs = o.providerTTLs
+	memory.precisionTTLs = o.precisionTTLs
+	var store Cache = memory
+	if o.secondaryCache != nil {
+		store = NewTieredCache(memory, o.secondaryCache)
 	}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return Result{}, err
-	}
-	defer resp.Body.Close()
+	s := &Service{
+		google:    google,
+		upstream:  providers[len(providers)-1],
+		providers: providers,
+		cache:     store,
+		memory:    memory,
+		churn:     newChurnGuard(o.churnLimit, o.churnWindow, o.now, o.churnNotify),
+		canonical: newCanonicalCache(o.canonicalCacheSize),
+		flight:    newFlightGroup(),
+		failures:  newFailureCache(o.failureTTL, o.noResultsTTL, o.now),
+		timezones: newTimezoneCache(o.cacheTTL, o.now),
+		health:    newProviderHealth(),
+		maxLookup: o.maxLookup,
 
-	if resp.StatusCode != http.StatusOK {
-		return Result{}, fmt.Errorf("google maps api returned status %d", resp.StatusCode)
+		providerTimeout: o.providerTimeout,
+		maxProviders:    o.maxProviders,
+		collisionGuard:  o.collisionGuard,
+		metrics:         o.metrics,
+		inflight:        newInflightLimit(o.maxInflight),
+		defaultCountry:  defaultCountry,
+		observeError:    o.errorObserver,
+		denials:         newDeniedStreak(o.deniedThreshold, o.onFatal),
+
+		coordinateMode:   o.coordinateMode,
+		minAddressLength: o.minAddressLength,
+		enrichers:        o.enrichers,
 	}
+	s.filter.Store(o.filter)
+	s.counters.Store(newCacheCounters(o.statsWindow, o.now))
+	return s
+}
+
+// NewService creates a configured Service instance. cacheTTL determines the lifetime of cache entries.
+//
+// Deprecated: use New with WithAPIKey and WithCacheTTL.
+func NewService(apiKey string, cacheTTL time.Duration) *Service {
+	// Keep the behavior this constructor always had: any cacheTTL is accepted, a non-positive one
+	// expiring entries right away, and addresses of any length are looked up.
+	o := defaultOptions()
+	o.apiKey = apiKey
+	o.cacheTTL = cacheTTL
+	o.minAddressLength = 0
+	return newService(o)
+}
 
-	var payload geocodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+// Geocode retrieves the coordinates for an address. It will use an in-memory cache before
+// querying the providers to keep the service responsive under heavy load.
+func (s *Service) Geocode(ctx context.Context, rawAddress string) (Result, error) {
+	form := s.canonicalize(rawAddress)
+	address := form.address
+	if address == "" {
+		return Result{}, ErrAddressRequired
+	}
+	if err := s.checkAddress(address); err != nil {
 		return Result{}, err
 	}
 
-	if payload.Status != "OK" {
-		if payload.ErrorMessage != "" {
-			return Result{}, fmt.Errorf("google maps api error: %s", payload.ErrorMessage)
-		}
-		return Result{}, fmt.Errorf("google maps api status: %s", payload.Status)
+	if s.coordinateMode == CoordinateInputReject && form.coordinates {
+		return Result{}, ErrCoordinatesInput
 	}
 
-	if len(payload.Results) == 0 {
-		return Result{}, ErrNoResults
+	key := form.key
+	result, err := s.resolve(ctx, key, rawAddress, func(ctx context.Context) (Result, error) {
+		return s.lookup(ctx, query{address: key})
+	})
+	if err != nil && s.coordinateMode == CoordinateInputFallback && form.coordinates && ctx.Err() == nil {
+		if lat, lng, ok := ParseCoordinates(address); ok {
+			trace := TraceFromContext(ctx)
+			trace.answered(SourceParsed, false)
+			trace.fellBack(0)
+			return Result{Address: address, Latitude: lat, Longitude: lng, Source: SourceParsed}, nil
+		}
 	}
+	return result, err
+}
 
-	top := payload.Results[0]
-	result := Result{
-		Address:   top.FormattedAddress,
-		Latitude:  top.Geometry.Location.Lat,
-		Longitude: top.Geometry.Location.Lng,
-		Source:    "google",
+// checkAddress rejects a normalized query shorter than the minimum address length or not allowed
+// by the address filter.
+func (s *Service) checkAddress(address string) error {
+	if utf8.RuneCountInString(address) < s.minAddressLength {
+		return ErrAddressTooShort
 	}
-
-	s.cache.Set(address, result)
-
-	return result, nil
+	return s.filter.Load().Check(address)
 }
 
-func normalizeAddress(address string) string {
-	return strings.TrimSpace(strings.ToLower(address))
+// augment applies the default country to a normalized address, leaving coordinates untouched.
+func (s *Service) augment(address string) string {
+	if _, _, ok := ParseCoordinates(address); ok {
+		return address
+	}
+	return s.defaultCountry.apply(address)
 }
 
-// geocodeResponse models the subset of the Google Geocoding API response that we require.
-type geocodeResponse struct {
-	Results []struct {
-		FormattedAddress string `json:"formatted_address"`
-		Geometry         struct {
-			Location struct {
-				Lat float64 `json:"lat"`
-				Lng float64 `json:"lng"`
-			} `json:"location"`
-		} `json:"geometry"`
-	} `json:"results"`
-	Status       string `json:"status"`
-	ErrorMessage string `json:"error_message"`
+// normalizeAddress builds the cache key for an address. It sits on the hot path of every request,
+// including cache hits, so it avoids allocating for input that is already normalized: TrimSpace
+// returns a substring of its input and ToLower returns its input unchanged when it has no
+// uppercase characters. Trimming first keeps ToLower from copying surrounding whitespace.
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
 }
 
 // cache is a minimal in-memory cache with TTL support used to avoid expensive API calls for repeated requests.
+// Entries are spread over shards by a hash of their key, each with its own lock, so lookups of
+// different addresses do not contend with each other or with writes.
 type cache struct {
-	ttl   time.Duration
-	items map[string]cacheItem
+	ttl    time.Duration
+	now    func() time.Time
+	shards []cacheShard
+	mask   uint32
+
+	// providerTTLs overrides ttl for results produced by a given provider, and precisionTTLs for
+	// results of a given precision. A provider TTL wins over a precision TTL.
+	providerTTLs  map[string]time.Duration
+	precisionTTLs map[string]time.Duration
+
+	// grace, when set and still running, stretches the TTL of every entry.
+	grace atomic.Pointer[GracePeriod]
+}
+
+type cacheShard struct {
 	mu    sync.RWMutex
+	items map[string]cacheItem
+	// bytes is the estimated memory held by items; see entrySize.
+	bytes int
 }
 
 type cacheItem struct {
 	value   Result
 	expires time.Time
+	// ttl is the lifetime the entry was stored with, which a grace period stretches.
+	ttl time.Duration
+}
+
+// newCache creates a cache with the given number of shards, which must be a power of two.
+func newCache(ttl time.Duration, now func() time.Time, shards int) *cache {
+	c := &cache{
+		ttl:    ttl,
+		now:    now,
+		shards: make([]cacheShard, shards),
+		mask:   uint32(shards - 1),
+	}
+	for i := range c.shards {
+		c.shards[i].items = make(map[string]cacheItem)
+	}
+	return c
 }
 
-func newCache(ttl time.Duration) *cache {
-	return &cache{
-		ttl:   ttl,
-		items: make(map[string]cacheItem),
+// shard returns the shard holding key, using an inlined FNV-1a hash to stay allocation free.
+func (c *cache) shard(key string) *cacheShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
 	}
+	return &c.shards[hash&c.mask]
 }
 
 func (c *cache) Get(key string) (Result, bool) {
-	c.mu.RLock()
-	item, ok := c.items[key]
-	c.mu.RUnlock()
+	shard := c.shard(key)
+	shard.mu.RLock()
+	item, ok := shard.items[key]
+	shard.mu.RUnlock()
 	if !ok {
 		return Result{}, false
 	}
-	if time.Now().After(item.expires) {
-		c.mu.Lock()
-		delete(c.items, key)
-		c.mu.Unlock()
+	if c.expired(item, c.now()) {
+		shard.mu.Lock()
+		shard.remove(key)
+		shard.mu.Unlock()
 		return Result{}, false
 	}
 	return item.value, true
 }
 
+// contains reports whether key has an entry, expired or not.
+func (c *cache) contains(key string) bool {
+	shard := c.shard(key)
+	shard.mu.RLock()
+	_, ok := shard.items[key]
+	shard.mu.RUnlock()
+	return ok
+}
+
 func (c *cache) Set(key string, value Result) {
-	c.mu.Lock()
-	c.items[key] = cacheItem{
+	ttl := c.ttlOf(value)
+	item := cacheItem{
 		value:   value,
-		expires: time.Now().Add(c.ttl),
+		expires: c.now().Add(ttl),
+		ttl:     ttl,
+	}
+	shard := c.shard(key)
+	shard.mu.Lock()
+	shard.put(key, item)
+	shard.mu.Unlock()
+}
+
+// ttlOf returns the lifetime of value: the TTL configured for the provider that produced it, else
+// the TTL configured for its precision, else the default TTL. Cached values keep the provider's
+// name in Source, so the entry is governed by its producer even when it came from a fallback.
+func (c *cache) ttlOf(value Result) time.Duration {
+	if ttl, ok := c.providerTTLs[value.Source]; ok {
+		return ttl
+	}
+	if ttl, ok := c.precisionTTLs[value.Precision]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+func (c *cache) Len() int {
+	total := 0
+	for i := range c.shards {
+		shard := &c.shards[i]
+		shard.mu.RLock()
+		total += len(shard.items)
+		shard.mu.RUnlock()
 	}
-	c.mu.Unlock()
+	return total
 }
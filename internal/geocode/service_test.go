@@ -0,0 +1,165 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a time source for WithClock that only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestService creates a Service with a placeholder API key and opts, failing the test on error.
+func newTestService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+	s, err := New(append([]Option{WithAPIKey("test-key")}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+// stubProvider is a Provider answering with answer and counting its calls.
+type stubProvider struct {
+	name   string
+	answer func(ctx context.Context, address string) (Result, error)
+	calls  atomic.Int32
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) Geocode(ctx context.Context, address string) (Result, error) {
+	p.calls.Add(1)
+	return p.answer(ctx, address)
+}
+
+// answerWith returns an answer function that resolves every address to result, with the address
+// as looked up.
+func answerWith(result Result) func(context.Context, string) (Result, error) {
+	return func(_ context.Context, address string) (Result, error) {
+		result.Address = address
+		return result, nil
+	}
+}
+
+func TestCacheHitKeepsFormattedAddressCasing(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	s := newTestService(t, google.option())
+	const formatted = "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil"
+
+	for _, input := range []string{"praça da sé", "  PRAÇA DA SÉ  ", "Praça da Sé"} {
+		result, err := s.Geocode(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Geocode(%q): %v", input, err)
+		}
+		if result.Address != formatted {
+			t.Errorf("Geocode(%q).Address = %q, want %q", input, result.Address, formatted)
+		}
+	}
+	if got := google.requests.Load(); got != 1 {
+		t.Fatalf("upstream requests = %d, want 1", got)
+	}
+}
+
+func BenchmarkNormalizeAddress(b *testing.B) {
+	inputs := []struct {
+		name    string
+		address string
+	}{
+		{name: "normalized", address: "praça da sé, são paulo"},
+		{name: "padded", address: "   praça da sé, são paulo   "},
+		{name: "mixed case", address: "  Praça da Sé, São Paulo "},
+	}
+	for _, input := range inputs {
+		b.Run(input.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				normalizeAddress(input.address)
+			}
+		})
+	}
+}
+
+func BenchmarkGeocodeCacheHit(b *testing.B) {
+	provider := &stubProvider{name: "stub", answer: answerWith(Result{Address: "Praça da Sé, São Paulo", Latitude: -23.55, Longitude: -46.63})}
+	s, err := New(WithAPIKey("test-key"), WithProviders(provider))
+	if err != nil {
+		b.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := s.Geocode(ctx, "praça da sé, são paulo"); err != nil {
+		b.Fatalf("Geocode: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := s.Geocode(ctx, "praça da sé, são paulo"); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	if provider.calls.Load() != 1 {
+		b.Fatalf("provider calls = %d, want every lookup after the first served from the cache", provider.calls.Load())
+	}
+}
+
+func TestGeocodeMinAddressLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		address   string
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "below the default", address: "Sé", wantErr: ErrAddressTooShort},
+		{name: "below after normalization", address: "   SÉ   ", wantErr: ErrAddressTooShort},
+		{name: "at the default", address: "Rio", wantCalls: 1},
+		{name: "multibyte at the default", address: "Sé!", wantCalls: 1},
+		{name: "below a custom minimum", opts: []Option{WithMinAddressLength(5)}, address: "0100", wantErr: ErrAddressTooShort},
+		{name: "at a custom minimum", opts: []Option{WithMinAddressLength(5)}, address: "01001", wantCalls: 1},
+		{name: "disabled", opts: []Option{WithMinAddressLength(0)}, address: "Sé", wantCalls: 1},
+		{name: "disabled still needs an address", opts: []Option{WithMinAddressLength(0)}, address: "   ", wantErr: ErrAddressRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{name: "stub", answer: answerWith(Result{Latitude: -23.55, Longitude: -46.63})}
+			s := newTestService(t, append(tt.opts, WithProviders(provider))...)
+
+			_, err := s.Geocode(context.Background(), tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Geocode(%q) = %v, want %v", tt.address, err, tt.wantErr)
+			}
+			if got := provider.calls.Load(); got != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package geocode
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// premiumCredentials holds Google Maps Platform premium plan credentials used to sign requests
+// instead of sending an API key.
+type premiumCredentials struct {
+	clientID string
+	key      []byte
+}
+
+// authorize adds the credentials to params and returns the final request URL for endpoint.
+func (p *GoogleProvider) authorize(endpoint string, params url.Values) (string, error) {
+	if p.channel != "" {
+		params.Set("channel", p.channel)
+	}
+	if p.premium == nil {
+		if p.apiKey == "" {
+			return "", ErrMissingAPIKey
+		}
+		params.Set("key", p.apiKey)
+		return endpoint + "?" + params.Encode(), nil
+	}
+
+	params.Set("client", p.premium.clientID)
+	return signURL(endpoint+"?"+params.Encode(), p.premium.key)
+}
+
+// signURL appends a signature computed as described in Google's URL signing documentation: an
+// HMAC-SHA1 over the path and query, keyed with the decoded private key and encoded as URL-safe
+// base64.
+func signURL(rawURL string, key []byte) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(parsed.EscapedPath() + "?" + parsed.RawQuery))
+	signature := base64.URLEncoding.EncodeToString(mac.Sum(nil))
+
+	return rawURL + "&signature=" + signature, nil
+}
+
+func decodeSigningKey(secret string) ([]byte, error) {
+	secret = strings.TrimSpace(secret)
+	// Google issues keys with padding, but tolerate keys whose padding was stripped.
+	if key, err := base64.URLEncoding.DecodeString(secret); err == nil {
+		return key, nil
+	}
+	return base64.RawURLEncoding.DecodeString(secret)
+}
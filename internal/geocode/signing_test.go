@@ -0,0 +1,114 @@
+package geocode
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+// googleExampleKey and the expected signature come from the worked example in Google's "Digital
+// signatures" documentation for the premium plan.
+const googleExampleKey = "vNIXE0xscrmjlyV-12Nj_BvUPaw="
+
+func TestSignURLMatchesGoogleExample(t *testing.T) {
+	key, err := decodeSigningKey(googleExampleKey)
+	if err != nil {
+		t.Fatalf("decodeSigningKey: %v", err)
+	}
+
+	got, err := signURL("https://maps.googleapis.com/maps/api/geocode/json?address=New+York&client=clientID", key)
+	if err != nil {
+		t.Fatalf("signURL: %v", err)
+	}
+	want := "https://maps.googleapis.com/maps/api/geocode/json?address=New+York&client=clientID&signature=chaRF2hTJKOScPr-RQCEhZbSzIE="
+	if got != want {
+		t.Fatalf("signURL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDecodeSigningKeyToleratesMissingPadding(t *testing.T) {
+	padded, err := decodeSigningKey(googleExampleKey)
+	if err != nil {
+		t.Fatalf("decodeSigningKey padded: %v", err)
+	}
+	unpadded, err := decodeSigningKey("vNIXE0xscrmjlyV-12Nj_BvUPaw")
+	if err != nil {
+		t.Fatalf("decodeSigningKey unpadded: %v", err)
+	}
+	if string(padded) != string(unpadded) {
+		t.Fatal("padded and unpadded keys decode differently")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	key, err := decodeSigningKey(googleExampleKey)
+	if err != nil {
+		t.Fatalf("decodeSigningKey: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		provider *GoogleProvider
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "api key",
+			provider: &GoogleProvider{apiKey: "secret"},
+			want:     geocodeEndpoint + "?address=New+York&key=secret",
+		},
+		{
+			name:     "api key with channel",
+			provider: &GoogleProvider{apiKey: "secret", channel: "checkout"},
+			want:     geocodeEndpoint + "?address=New+York&channel=checkout&key=secret",
+		},
+		{
+			name:     "premium",
+			provider: &GoogleProvider{apiKey: "ignored", premium: &premiumCredentials{clientID: "clientID", key: key}},
+			want:     geocodeEndpoint + "?address=New+York&client=clientID&signature=chaRF2hTJKOScPr-RQCEhZbSzIE=",
+		},
+		{
+			name:     "no credentials",
+			provider: &GoogleProvider{},
+			wantErr:  ErrMissingAPIKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.authorize(geocodeEndpoint, url.Values{"address": {"New York"}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorize error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("authorize =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPremiumCredentialsSignChannel(t *testing.T) {
+	key, err := decodeSigningKey(googleExampleKey)
+	if err != nil {
+		t.Fatalf("decodeSigningKey: %v", err)
+	}
+	provider := &GoogleProvider{channel: "checkout", premium: &premiumCredentials{clientID: "clientID", key: key}}
+
+	got, err := provider.authorize(geocodeEndpoint, url.Values{"address": {"New York"}})
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	// The channel is part of the signed portion, so the signature must cover it.
+	want, err := signURL(geocodeEndpoint+"?address=New+York&channel=checkout&client=clientID", key)
+	if err != nil {
+		t.Fatalf("signURL: %v", err)
+	}
+	if got != want {
+		t.Fatalf("authorize =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWithPremiumCredentialsRejectsInvalidSecret(t *testing.T) {
+	if _, err := New(WithPremiumCredentials("clientID", "not base64!")); err == nil {
+		t.Fatal("expected an error for an invalid signing secret")
+	}
+}
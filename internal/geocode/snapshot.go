@@ -0,0 +1,96 @@
+package geocode
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotEntry is the on-disk representation of a cache entry. Expiry is stored as an absolute
+// timestamp so entries keep their remaining lifetime across restarts.
+type snapshotEntry struct {
+	Key     string    `json:"key"`
+	Value   Result    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// SaveSnapshot writes all non-expired in-memory cache entries to path as JSON. The file is written atomically
+// through a temporary file so a crash mid-write never leaves a truncated snapshot behind.
+func (s *Service) SaveSnapshot(path string) error {
+	entries := s.memory.entries()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(entries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot restores cache entries from a snapshot written by SaveSnapshot. Entries that expired
+// while the service was down are discarded. A missing snapshot file is not an error. It returns
+// the number of entries restored.
+func (s *Service) LoadSnapshot(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer file.Close()
+
+	var entries []snapshotEntry
+	if err := json.NewDecoder(file).Decode(&entries); err != nil {
+		return 0, err
+	}
+
+	return s.memory.restore(entries), nil
+}
+
+func (c *cache) entries() []snapshotEntry {
+	now := c.now()
+
+	entries := make([]snapshotEntry, 0, c.Len())
+	for i := range c.shards {
+		shard := &c.shards[i]
+		shard.mu.RLock()
+		for key, item := range shard.items {
+			if now.After(item.expires) {
+				continue
+			}
+			entries = append(entries, snapshotEntry{Key: key, Value: item.value, Expires: item.expires})
+		}
+		shard.mu.RUnlock()
+	}
+	return entries
+}
+
+func (c *cache) restore(entries []snapshotEntry) int {
+	now := c.now()
+	restored := 0
+
+	for _, entry := range entries {
+		if now.After(entry.Expires) {
+			continue
+		}
+		shard := c.shard(entry.Key)
+		shard.mu.Lock()
+		shard.put(entry.Key, cacheItem{value: entry.Value, expires: entry.Expires, ttl: c.ttlOf(entry.Value)})
+		shard.mu.Unlock()
+		restored++
+	}
+	return restored
+}
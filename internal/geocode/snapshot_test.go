@@ -0,0 +1,78 @@
+package geocode
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	clock := newFakeClock()
+	saved := newTestService(t, WithClock(clock.Now), WithCacheTTL(time.Hour))
+
+	// "old" expires 10 minutes after the snapshot is taken, "new" 30 minutes after, and "stale" has
+	// already expired when it is taken.
+	saved.cache.Set("stale", Result{Address: "Stale"})
+	clock.Advance(30 * time.Minute)
+	saved.cache.Set("old", Result{Address: "Old", Latitude: 1, Longitude: 2})
+	clock.Advance(20 * time.Minute)
+	saved.cache.Set("new", Result{Address: "New", Latitude: 3, Longitude: 4})
+	clock.Advance(30 * time.Minute)
+	newExpiry := clock.Now().Add(30 * time.Minute)
+
+	path := filepath.Join(t.TempDir(), "cache-snapshot.json")
+	if err := saved.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	if entries := saved.memory.entries(); len(entries) != 2 {
+		t.Fatalf("snapshot holds %d entries, want 2 (the expired one is skipped)", len(entries))
+	}
+
+	tests := []struct {
+		name     string
+		downtime time.Duration
+		want     []string
+	}{
+		{name: "quick restart", downtime: time.Minute, want: []string{"old", "new"}},
+		{name: "long restart drops expired entries", downtime: 20 * time.Minute, want: []string{"new"}},
+		{name: "everything expired", downtime: 2 * time.Hour, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadClock := &fakeClock{now: clock.Now().Add(tt.downtime)}
+			loaded := newTestService(t, WithClock(loadClock.Now), WithCacheTTL(time.Hour))
+
+			restored, err := loaded.LoadSnapshot(path)
+			if err != nil {
+				t.Fatalf("LoadSnapshot: %v", err)
+			}
+			if restored != len(tt.want) {
+				t.Errorf("restored %d entries, want %d", restored, len(tt.want))
+			}
+			for _, key := range tt.want {
+				if _, ok := loaded.cache.Get(key); !ok {
+					t.Errorf("entry %q was not restored", key)
+				}
+			}
+			if _, ok := loaded.cache.Get("stale"); ok {
+				t.Error("expired entry was restored")
+			}
+
+			// Restored entries keep their original expiry instead of a fresh TTL.
+			if len(tt.want) > 0 {
+				shard := loaded.memory.shard("new")
+				if got := shard.items["new"].expires; !got.Equal(newExpiry) {
+					t.Errorf("restored expiry = %s, want %s", got, newExpiry)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSnapshotMissingFile(t *testing.T) {
+	s := newTestService(t)
+	restored, err := s.LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || restored != 0 {
+		t.Fatalf("LoadSnapshot = %d, %v; want 0, nil", restored, err)
+	}
+}
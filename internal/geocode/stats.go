@@ -0,0 +1,152 @@
+package geocode
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsBuckets is the number of buckets the sliding window is split into. More buckets give a
+// smoother ratio at the cost of a slightly larger fixed memory footprint.
+const statsBuckets = 60
+
+// DefaultStatsWindow is the sliding window used for the cache hit ratio when none is configured.
+const DefaultStatsWindow = 5 * time.Minute
+
+// CacheStats summarizes cache effectiveness over the lifetime of the process and over a recent window.
+type CacheStats struct {
+	Entries        int     `json:"entries"`
+	Hits           uint64  `json:"hits"`
+	Misses         uint64  `json:"misses"`
+	HitRatio       float64 `json:"hit_ratio"`
+	Window         string  `json:"window"`
+	WindowHits     uint64  `json:"window_hits"`
+	WindowMisses   uint64  `json:"window_misses"`
+	WindowHitRatio float64 `json:"window_hit_ratio"`
+	// CachingPaused is set while new entries are not cached because too many new keys arrived.
+	CachingPaused bool `json:"caching_paused"`
+	// EstimatedBytes is a rough estimate of the memory held by the in-memory cache entries, kept up
+	// to date as entries are stored and evicted.
+	EstimatedBytes int `json:"estimated_bytes"`
+	// GracePeriod is the grace period stretching entry lifetimes, while one is running.
+	GracePeriod *GracePeriod `json:"grace_period,omitempty"`
+}
+
+// cacheCounters tracks lifetime cache hits and misses alongside a sliding window of recent ones.
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	window *hitWindow
+	now    func() time.Time
+}
+
+func newCacheCounters(window time.Duration, now func() time.Time) *cacheCounters {
+	return &cacheCounters{window: newHitWindow(window), now: now}
+}
+
+func (c *cacheCounters) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	c.window.record(c.now(), hit)
+}
+
+// hitWindow is a ring buffer of fixed-width time buckets. Buckets are lazily reset when the ring
+// wraps around, so recording and reading are O(1) and O(statsBuckets) respectively.
+type hitWindow struct {
+	mu         sync.Mutex
+	span       time.Duration
+	bucketSize time.Duration
+	buckets    [statsBuckets]hitBucket
+}
+
+type hitBucket struct {
+	epoch  int64
+	hits   uint64
+	misses uint64
+}
+
+func newHitWindow(span time.Duration) *hitWindow {
+	if span <= 0 {
+		span = DefaultStatsWindow
+	}
+	bucketSize := span / statsBuckets
+	if bucketSize <= 0 {
+		bucketSize = 1
+	}
+	return &hitWindow{span: span, bucketSize: bucketSize}
+}
+
+func (w *hitWindow) record(now time.Time, hit bool) {
+	epoch := now.UnixNano() / int64(w.bucketSize)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bucket := &w.buckets[epoch%statsBuckets]
+	if bucket.epoch != epoch {
+		*bucket = hitBucket{epoch: epoch}
+	}
+	if hit {
+		bucket.hits++
+	} else {
+		bucket.misses++
+	}
+}
+
+func (w *hitWindow) totals(now time.Time) (hits, misses uint64) {
+	epoch := now.UnixNano() / int64(w.bucketSize)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, bucket := range w.buckets {
+		if bucket.epoch > epoch-statsBuckets && bucket.epoch <= epoch {
+			hits += bucket.hits
+			misses += bucket.misses
+		}
+	}
+	return hits, misses
+}
+
+// CacheStats reports the current in-memory cache size and hit ratios.
+func (s *Service) CacheStats() CacheStats {
+	counters := s.counters.Load()
+	hits, misses := counters.hits.Load(), counters.misses.Load()
+	windowHits, windowMisses := counters.window.totals(counters.now())
+
+	stats := CacheStats{
+		Entries:        s.memory.Len(),
+		Hits:           hits,
+		Misses:         misses,
+		HitRatio:       ratio(hits, misses),
+		Window:         counters.window.span.String(),
+		WindowHits:     windowHits,
+		WindowMisses:   windowMisses,
+		WindowHitRatio: ratio(windowHits, windowMisses),
+		CachingPaused:  s.churn.isPaused(),
+		EstimatedBytes: s.memory.EstimatedBytes(),
+	}
+	if grace, ok := s.memory.activeGrace(s.memory.now()); ok {
+		stats.GracePeriod = &grace
+	}
+	return stats
+}
+
+// ResetCacheStats zeroes the hit and miss counters, including the sliding window. The counters are
+// swapped as a whole, so a concurrent CacheStats sees either the old or the new values, never a mix.
+// Cache entries are kept.
+func (s *Service) ResetCacheStats() {
+	current := s.counters.Load()
+	s.counters.Store(newCacheCounters(current.window.span, current.now))
+}
+
+func ratio(hits, misses uint64) float64 {
+	total := hits + misses
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
@@ -0,0 +1,115 @@
+package geocode
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheStatsSlidingWindow(t *testing.T) {
+	clock := newFakeClock()
+	s := newTestService(t,
+		WithClock(clock.Now),
+		WithStatsWindow(time.Minute),
+		WithProviders(NewStaticProvider([]StaticEntry{{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63}})),
+	)
+	geocode := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := s.Geocode(context.Background(), "Praça da Sé"); err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		advance              time.Duration
+		lookups              int
+		wantHits, wantMisses uint64
+		wantRatio            float64
+		wantWindowHits       uint64
+		wantWindowMisses     uint64
+		wantWindowRatio      float64
+	}{
+		{
+			name:     "first miss then hits",
+			lookups:  4,
+			wantHits: 3, wantMisses: 1, wantRatio: 0.75,
+			wantWindowHits: 3, wantWindowMisses: 1, wantWindowRatio: 0.75,
+		},
+		{
+			name:     "within the window",
+			advance:  30 * time.Second,
+			lookups:  4,
+			wantHits: 7, wantMisses: 1, wantRatio: 0.875,
+			wantWindowHits: 7, wantWindowMisses: 1, wantWindowRatio: 0.875,
+		},
+		{
+			name:     "the initial miss leaves the window",
+			advance:  45 * time.Second,
+			lookups:  1,
+			wantHits: 8, wantMisses: 1, wantRatio: 8.0 / 9,
+			wantWindowHits: 5, wantWindowMisses: 0, wantWindowRatio: 1,
+		},
+		{
+			name:     "idle past the window",
+			advance:  2 * time.Minute,
+			wantHits: 8, wantMisses: 1, wantRatio: 8.0 / 9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			geocode(tt.lookups)
+
+			stats := s.CacheStats()
+			if stats.Hits != tt.wantHits || stats.Misses != tt.wantMisses || stats.HitRatio != tt.wantRatio {
+				t.Errorf("lifetime = %d hits, %d misses, ratio %v; want %d, %d, %v",
+					stats.Hits, stats.Misses, stats.HitRatio, tt.wantHits, tt.wantMisses, tt.wantRatio)
+			}
+			if stats.WindowHits != tt.wantWindowHits || stats.WindowMisses != tt.wantWindowMisses || stats.WindowHitRatio != tt.wantWindowRatio {
+				t.Errorf("window = %d hits, %d misses, ratio %v; want %d, %d, %v",
+					stats.WindowHits, stats.WindowMisses, stats.WindowHitRatio, tt.wantWindowHits, tt.wantWindowMisses, tt.wantWindowRatio)
+			}
+		})
+	}
+}
+
+func TestResetCacheStats(t *testing.T) {
+	s := newTestService(t, WithProviders(NewStaticProvider([]StaticEntry{{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63}})))
+	for i := 0; i < 3; i++ {
+		if _, err := s.Geocode(context.Background(), "Praça da Sé"); err != nil {
+			t.Fatalf("Geocode: %v", err)
+		}
+	}
+
+	s.ResetCacheStats()
+	got := s.CacheStats()
+	if got.Hits != 0 || got.Misses != 0 || got.HitRatio != 0 || got.WindowHits != 0 || got.WindowMisses != 0 || got.WindowHitRatio != 0 {
+		t.Errorf("stats after reset = %+v, want zero counters", got)
+	}
+	if got.Entries != 1 {
+		t.Errorf("entries after reset = %d, want the cache kept", got.Entries)
+	}
+}
+
+func TestResetCacheStatsWhileScraping(t *testing.T) {
+	s := newTestService(t, WithProviders(NewStaticProvider([]StaticEntry{{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63}})))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			s.Geocode(context.Background(), "Praça da Sé")
+			s.CacheStats()
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		s.ResetCacheStats()
+	}
+	<-done
+
+	s.ResetCacheStats()
+	if got := s.CacheStats(); got.Hits != 0 || got.Misses != 0 {
+		t.Errorf("hits %d and misses %d after the final reset, want 0", got.Hits, got.Misses)
+	}
+}
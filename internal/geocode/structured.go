@@ -0,0 +1,82 @@
+package geocode
+
+import (
+	"context"
+	"strings"
+)
+
+// structuredCacheKeyPrefix namespaces structured lookups so they never collide with free-text or
+// postal code lookups in the cache.
+const structuredCacheKeyPrefix = "structured:"
+
+// StructuredAddress is an address already split into fields. Every field is optional, but at least
+// one must be set.
+type StructuredAddress struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+// GeocodeStructured resolves an address given as separate fields through the provider chain, like
+// Geocode. The postal code and country are sent as Google components filters, which are matched
+// exactly, and the remaining fields as free text, so the structure is not lost to a single
+// concatenated string. With a filter, only providers supporting filters are tried. The minimum
+// address length and the address filter apply to every field together, so a blocked value is
+// rejected whichever field it is sent in.
+func (s *Service) GeocodeStructured(ctx context.Context, address StructuredAddress) (Result, error) {
+	text, components, err := structuredQuery(address)
+	if err != nil {
+		return Result{}, err
+	}
+	if text == "" && components == "" {
+		return Result{}, ErrAddressRequired
+	}
+	if err := s.checkAddress(structuredFilterText(address)); err != nil {
+		return Result{}, err
+	}
+
+	key := structuredCacheKeyPrefix + text + "|" + components
+	return s.resolve(ctx, key, "", func(ctx context.Context) (Result, error) {
+		return s.lookup(ctx, query{address: text, components: components})
+	})
+}
+
+// structuredQuery builds the free-text part and the components filter of a structured lookup. Both
+// are normalized, so equivalent inputs produce the same query and share a cache entry.
+func structuredQuery(address StructuredAddress) (text, components string, err error) {
+	var parts []string
+	for _, field := range []string{address.Street, address.City, address.State} {
+		if field = normalizeAddress(field); field != "" {
+			parts = append(parts, field)
+		}
+	}
+
+	postalCode := strings.ToUpper(strings.TrimSpace(address.PostalCode))
+	country := strings.ToUpper(strings.TrimSpace(address.Country))
+	if strings.ContainsAny(postalCode+country, ":|") {
+		return "", "", ErrInvalidComponent
+	}
+	var filters []string
+	if postalCode != "" {
+		filters = append(filters, "postal_code:"+postalCode)
+	}
+	if country != "" {
+		filters = append(filters, "country:"+country)
+	}
+
+	return strings.Join(parts, ", "), strings.Join(filters, "|"), nil
+}
+
+// structuredFilterText joins every field of a structured address, normalized, into the single
+// string checked against the minimum address length and the address filter.
+func structuredFilterText(address StructuredAddress) string {
+	var parts []string
+	for _, field := range []string{address.Street, address.City, address.State, address.PostalCode, address.Country} {
+		if field = normalizeAddress(field); field != "" {
+			parts = append(parts, field)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
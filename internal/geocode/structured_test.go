@@ -0,0 +1,143 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestGeocodeStructuredUpstreamQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    StructuredAddress
+		wantQuery  url.Values
+		wantErr    error
+		wantCalled bool
+	}{
+		{
+			name:       "every field",
+			address:    StructuredAddress{Street: " Praça da Sé ", City: "São Paulo", State: "SP", PostalCode: "01001-000", Country: "br"},
+			wantQuery:  url.Values{"address": {"praça da sé, são paulo, sp"}, "components": {"postal_code:01001-000|country:BR"}},
+			wantCalled: true,
+		},
+		{
+			name:       "free text only",
+			address:    StructuredAddress{Street: "Avenida Paulista, 1000", City: "SÃO PAULO"},
+			wantQuery:  url.Values{"address": {"avenida paulista, 1000, são paulo"}},
+			wantCalled: true,
+		},
+		{
+			name:       "components only",
+			address:    StructuredAddress{PostalCode: "01310-100", Country: "BR"},
+			wantQuery:  url.Values{"components": {"postal_code:01310-100|country:BR"}},
+			wantCalled: true,
+		},
+		{name: "empty", address: StructuredAddress{Street: "  "}, wantErr: ErrAddressRequired},
+		{name: "filter separator in a component", address: StructuredAddress{Street: "Praça da Sé", Country: "BR|country:US"}, wantErr: ErrInvalidComponent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				query url.Values
+			)
+			google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				query = r.URL.Query()
+				mu.Unlock()
+				respond(http.StatusOK, sePayload)(w, r)
+			})
+			s := newTestService(t, google.option())
+
+			_, err := s.GeocodeStructured(context.Background(), tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GeocodeStructured = %v, want %v", err, tt.wantErr)
+			}
+			if called := google.requests.Load() > 0; called != tt.wantCalled {
+				t.Fatalf("Google called = %v, want %v", called, tt.wantCalled)
+			}
+			if !tt.wantCalled {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			query.Del("key")
+			if query.Encode() != tt.wantQuery.Encode() {
+				t.Errorf("upstream query = %s, want %s", query.Encode(), tt.wantQuery.Encode())
+			}
+		})
+	}
+}
+
+func TestGeocodeStructuredCacheKeyIsStable(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+	s := newTestService(t, google.option())
+	sé := StructuredAddress{Street: "Praça da Sé", City: "São Paulo", PostalCode: "01001-000", Country: "BR"}
+
+	tests := []struct {
+		name         string
+		address      StructuredAddress
+		wantSource   string
+		wantRequests int32
+	}{
+		{name: "first lookup", address: sé, wantSource: "google", wantRequests: 1},
+		{name: "same fields", address: sé, wantSource: "cache", wantRequests: 1},
+		{name: "equivalent spelling", address: StructuredAddress{Street: "  PRAÇA DA SÉ", City: "são paulo ", PostalCode: " 01001-000", Country: "br"},
+			wantSource: "cache", wantRequests: 1},
+		{name: "different postal code", address: StructuredAddress{Street: "Praça da Sé", City: "São Paulo", PostalCode: "01002-000", Country: "BR"},
+			wantSource: "google", wantRequests: 2},
+	}
+	for _, tt := range tests {
+		result, err := s.GeocodeStructured(context.Background(), tt.address)
+		if err != nil {
+			t.Fatalf("%s: GeocodeStructured: %v", tt.name, err)
+		}
+		if result.Source != tt.wantSource {
+			t.Errorf("%s: source = %s, want %s", tt.name, result.Source, tt.wantSource)
+		}
+		if got := google.requests.Load(); got != tt.wantRequests {
+			t.Errorf("%s: upstream requests = %d, want %d", tt.name, got, tt.wantRequests)
+		}
+	}
+
+	// A free-text lookup of the same words does not share the structured entry.
+	if result, err := s.Geocode(context.Background(), "praça da sé, são paulo"); err != nil || result.Source != "google" {
+		t.Errorf("free-text Geocode = %s, %v; want a fresh google lookup", result.Source, err)
+	}
+}
+
+func TestGeocodeStructuredChecksEveryField(t *testing.T) {
+	filter, err := NewFilter([]string{"rua proibida", "99999-000", "re:, kp$"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	tests := []struct {
+		name    string
+		address StructuredAddress
+		wantErr error
+	}{
+		{name: "blocked street", address: StructuredAddress{Street: "Rua Proibida, 10", Country: "BR"}, wantErr: ErrAddressBlocked},
+		{name: "blocked postal code", address: StructuredAddress{Street: "Rua Permitida, 10", PostalCode: "99999-000", Country: "BR"}, wantErr: ErrAddressBlocked},
+		{name: "blocked country", address: StructuredAddress{City: "Pyongyang", Country: "KP"}, wantErr: ErrAddressBlocked},
+		{name: "country alone is too short", address: StructuredAddress{Country: "KP"}, wantErr: ErrAddressTooShort},
+		{name: "too short", address: StructuredAddress{City: "X"}, wantErr: ErrAddressTooShort},
+		{name: "short fields long enough together", address: StructuredAddress{City: "X", Country: "BR"}},
+		{name: "allowed", address: StructuredAddress{Street: "Rua Permitida, 10", PostalCode: "01001-000", Country: "BR"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+			s := newTestService(t, google.option(), WithFilter(filter))
+
+			if _, err := s.GeocodeStructured(context.Background(), tt.address); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GeocodeStructured = %v, want %v", err, tt.wantErr)
+			}
+			if called := google.requests.Load() > 0; called != (tt.wantErr == nil) {
+				t.Errorf("Google called = %v, want %v", called, tt.wantErr == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package geocode
+
+// minSweepSize is the number of entries below which caches pruned on write are never swept.
+const minSweepSize = 1024
+
+// sweepSchedule decides when a cache that otherwise only drops expired entries as they are read
+// should scan for all of them: whenever it has doubled in size since its last sweep. The cost of a
+// sweep is thus amortized over the writes that grew the cache, and entries for keys that are never
+// read again cannot accumulate without bound. It is guarded by the cache's own lock.
+type sweepSchedule struct {
+	next int
+}
+
+// due reports whether a cache holding size entries should be swept before its next write.
+func (s *sweepSchedule) due(size int) bool {
+	return size >= max(s.next, minSweepSize)
+}
+
+// swept records the size left after a sweep.
+func (s *sweepSchedule) swept(size int) {
+	s.next = 2 * size
+}
@@ -0,0 +1,49 @@
+package geocode
+
+// Strategies for choosing among several results returned for one address.
+const (
+	// TieBreakFirst keeps Google's first result. Google does not guarantee the order of results, so
+	// repeated lookups of an ambiguous address may pick different ones.
+	TieBreakFirst = "first"
+	// TieBreakAddress keeps the most precise results and, among those, the one whose formatted
+	// address sorts first, so the same response always yields the same choice.
+	TieBreakAddress = "address"
+)
+
+// precisionRank orders location types from most to least precise. Unknown types rank last.
+var precisionRank = map[string]int{
+	PrecisionRooftop:           0,
+	PrecisionRangeInterpolated: 1,
+	PrecisionGeometricCenter:   2,
+	PrecisionApproximate:       3,
+}
+
+func rankOf(locationType string) int {
+	if rank, ok := precisionRank[locationType]; ok {
+		return rank
+	}
+	return len(precisionRank)
+}
+
+// pickResult selects one of results, which must not be empty, according to strategy.
+func pickResult(results []geocodeResult, strategy string) geocodeResult {
+	best := results[0]
+	if strategy != TieBreakAddress {
+		return best
+	}
+	for _, candidate := range results[1:] {
+		rank, bestRank := rankOf(candidate.Geometry.LocationType), rankOf(best.Geometry.LocationType)
+		if rank < bestRank || (rank == bestRank && candidate.FormattedAddress < best.FormattedAddress) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// TieBreak returns the strategy used to choose among several Google results.
+func (s *Service) TieBreak() string {
+	if s.google.tieBreak == "" {
+		return TieBreakFirst
+	}
+	return s.google.tieBreak
+}
@@ -0,0 +1,54 @@
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// tieBreakPayload is a Geocoding API response listing results, each given as "address|location type".
+func tieBreakPayload(results ...string) string {
+	entries := make([]string, len(results))
+	for i, r := range results {
+		address, locationType, _ := strings.Cut(r, "|")
+		entries[i] = fmt.Sprintf(`{"formatted_address": %q, "geometry": {"location": {"lat": %d, "lng": %d}, "location_type": %q}}`,
+			address, -i, -i, locationType)
+	}
+	return `{"status": "OK", "results": [` + strings.Join(entries, ",") + `]}`
+}
+
+func TestGeocodeTieBreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		results  []string
+		want     string
+	}{
+		{name: "first keeps the order", strategy: TieBreakFirst,
+			results: []string{"Rua B, São Paulo|GEOMETRIC_CENTER", "Rua A, São Paulo|GEOMETRIC_CENTER"}, want: "Rua B, São Paulo"},
+		{name: "address sorts equal precisions", strategy: TieBreakAddress,
+			results: []string{"Rua B, São Paulo|GEOMETRIC_CENTER", "Rua A, São Paulo|GEOMETRIC_CENTER"}, want: "Rua A, São Paulo"},
+		{name: "address ignores the order", strategy: TieBreakAddress,
+			results: []string{"Rua A, São Paulo|GEOMETRIC_CENTER", "Rua B, São Paulo|GEOMETRIC_CENTER"}, want: "Rua A, São Paulo"},
+		{name: "address prefers precision", strategy: TieBreakAddress,
+			results: []string{"Rua A, São Paulo|APPROXIMATE", "Rua C, São Paulo|ROOFTOP", "Rua B, São Paulo|ROOFTOP"}, want: "Rua B, São Paulo"},
+		{name: "address ranks unknown precision last", strategy: TieBreakAddress,
+			results: []string{"Rua A, São Paulo|", "Rua B, São Paulo|APPROXIMATE"}, want: "Rua B, São Paulo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, tieBreakPayload(tt.results...)))
+			s := newTestService(t, google.option(), WithTieBreak(tt.strategy))
+
+			result, err := s.Geocode(context.Background(), "Rua Direita")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Address != tt.want {
+				t.Errorf("address = %q, want %q", result.Address, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package geocode
+
+// Cache stores geocoding results by normalized key. Implementations that talk to remote stores
+// must degrade gracefully: errors are reported as misses on Get and dropped on Set, so a cache
+// outage never fails a lookup.
+type Cache interface {
+	Get(key string) (Result, bool)
+	Set(key string, value Result)
+}
+
+// TieredCache combines a fast local cache with a shared secondary one. Reads check L1 first, then
+// L2, populating L1 on an L2 hit; writes go to both tiers.
+type TieredCache struct {
+	l1 Cache
+	l2 Cache
+}
+
+// NewTieredCache creates a read-through cache over l1 and l2.
+func NewTieredCache(l1, l2 Cache) *TieredCache {
+	return &TieredCache{l1: l1, l2: l2}
+}
+
+// Get returns the value from the first tier that has it.
+func (c *TieredCache) Get(key string) (Result, bool) {
+	if value, ok := c.l1.Get(key); ok {
+		return value, true
+	}
+	value, ok := c.l2.Get(key)
+	if !ok {
+		return Result{}, false
+	}
+	c.l1.Set(key, value)
+	return value, true
+}
+
+// Set writes value to both tiers.
+func (c *TieredCache) Set(key string, value Result) {
+	c.l1.Set(key, value)
+	c.l2.Set(key, value)
+}
+
+// WithSecondaryCache adds a shared L2 cache, such as a Redis-backed implementation, behind the
+// in-memory cache.
+func WithSecondaryCache(l2 Cache) Option {
+	return func(o *options) error {
+		o.secondaryCache = l2
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package geocode
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// mapCache is a Cache backed by a map that counts its reads and writes.
+type mapCache struct {
+	mu         sync.Mutex
+	entries    map[string]Result
+	gets, sets int
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{entries: make(map[string]Result)}
+}
+
+func (c *mapCache) Get(key string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+func (c *mapCache) Set(key string, value Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets++
+	c.entries[key] = value
+}
+
+func TestTieredCache(t *testing.T) {
+	sé := Result{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63}
+
+	tests := []struct {
+		name         string
+		l1, l2       map[string]Result
+		wantOK       bool
+		wantL2Gets   int
+		wantL1Filled bool
+	}{
+		{name: "L1 hit", l1: map[string]Result{"sé": sé}, l2: map[string]Result{}, wantOK: true, wantL1Filled: true},
+		{name: "L2 hit", l1: map[string]Result{}, l2: map[string]Result{"sé": sé}, wantOK: true, wantL2Gets: 1, wantL1Filled: true},
+		{name: "miss", l1: map[string]Result{}, l2: map[string]Result{}, wantL2Gets: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l1, l2 := newMapCache(), newMapCache()
+			l1.entries, l2.entries = tt.l1, tt.l2
+			c := NewTieredCache(l1, l2)
+
+			got, ok := c.Get("sé")
+			if ok != tt.wantOK || (ok && got.Address != sé.Address) {
+				t.Fatalf("Get = %+v, %v, want hit %v", got, ok, tt.wantOK)
+			}
+			if l2.gets != tt.wantL2Gets {
+				t.Errorf("L2 reads = %d, want %d", l2.gets, tt.wantL2Gets)
+			}
+			if _, filled := l1.entries["sé"]; filled != tt.wantL1Filled {
+				t.Errorf("L1 holds the entry = %v, want %v", filled, tt.wantL1Filled)
+			}
+		})
+	}
+}
+
+func TestTieredCacheWritesThroughOnMiss(t *testing.T) {
+	l2 := newMapCache()
+	upstream := &stubProvider{name: "upstream", answer: answerWith(Result{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63})}
+	first := newTestService(t, WithSecondaryCache(l2), WithProviders(upstream))
+
+	if _, err := first.Geocode(context.Background(), "Praça da Sé"); err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+	if l2.sets != 1 || len(l2.entries) != 1 {
+		t.Fatalf("L2 writes = %d with %d entries, want the result written through", l2.sets, len(l2.entries))
+	}
+
+	// Another instance sharing the L2 answers from it without calling its providers.
+	second := newTestService(t, WithSecondaryCache(l2), WithProviders(upstream))
+	result, err := second.Geocode(context.Background(), "Praça da Sé")
+	if err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+	if result.Source != "cache" || result.Latitude != -23.55 {
+		t.Errorf("result = %s at %v, want the cached entry", result.Source, result.Latitude)
+	}
+	if got := upstream.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+}
@@ -0,0 +1,209 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const timezoneEndpoint = "https://maps.googleapis.com/maps/api/timezone/json"
+
+// ErrInvalidCoordinates is returned when a latitude or longitude is out of range.
+var ErrInvalidCoordinates = errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+
+// timezoneBucket groups timestamps for caching. DST transitions happen on the hour, so an hourly
+// bucket keeps cached offsets correct.
+const timezoneBucket = time.Hour
+
+// timezoneKeyDecimals rounds coordinates for the cache key. Two decimals (about 1 km) is far finer
+// than any time zone boundary that matters in practice.
+const timezoneKeyDecimals = 2
+
+// TimeZone describes the time zone of a coordinate at a given moment. Offsets are in seconds.
+type TimeZone struct {
+	TimeZoneID   string `json:"time_zone_id"`
+	TimeZoneName string `json:"time_zone_name"`
+	RawOffset    int    `json:"raw_offset"`
+	DSTOffset    int    `json:"dst_offset"`
+	Source       string `json:"source,omitempty"`
+}
+
+// TimeZone returns the time zone for a coordinate at the given time, using the Google Time Zone API.
+func (s *Service) TimeZone(ctx context.Context, lat, lng float64, at time.Time) (TimeZone, error) {
+	// Written so that NaN, which fails every comparison, is rejected too.
+	if !(lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180) {
+		return TimeZone{}, ErrInvalidCoordinates
+	}
+
+	bucket := at.Truncate(timezoneBucket)
+	key := timezoneKey(lat, lng, bucket)
+	if tz, ok := s.timezones.Get(key); ok {
+		tz.Source = "cache"
+		return tz, nil
+	}
+
+	if !s.inflight.tryAcquire() {
+		return TimeZone{}, ErrOverloaded
+	}
+	start := time.Now()
+	var tz TimeZone
+	err := s.attempt(ctx, s.upstream, func(ctx context.Context) (err error) {
+		tz, err = timeZoneOf(ctx, s.upstream, lat, lng, bucket)
+		return err
+	})
+	recordUpstream(ctx, start)
+	s.inflight.release()
+	if err != nil {
+		s.observeError(err)
+		return TimeZone{}, err
+	}
+
+	s.timezones.Set(key, tz)
+
+	return tz, nil
+}
+
+// timeZoneOf asks provider for a time zone, answering ErrNoResults if it has none.
+func timeZoneOf(ctx context.Context, provider Provider, lat, lng float64, at time.Time) (TimeZone, error) {
+	timeZoner, ok := provider.(TimeZoner)
+	if !ok {
+		return TimeZone{}, ErrNoResults
+	}
+	return timeZoner.TimeZone(ctx, lat, lng, at)
+}
+
+func timezoneKey(lat, lng float64, bucket time.Time) string {
+	scale := math.Pow10(timezoneKeyDecimals)
+	return fmt.Sprintf("%.*f,%.*f@%d",
+		timezoneKeyDecimals, math.Round(lat*scale)/scale,
+		timezoneKeyDecimals, math.Round(lng*scale)/scale,
+		bucket.Unix(),
+	)
+}
+
+// TimeZone queries the Google Time Zone API for a coordinate at the given time.
+func (p *GoogleProvider) TimeZone(ctx context.Context, lat, lng float64, at time.Time) (TimeZone, error) {
+	apiURL, err := p.authorize(timezoneEndpoint, url.Values{
+		"location":  {strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lng, 'f', -1, 64)},
+		"timestamp": {strconv.FormatInt(at.Unix(), 10)},
+	})
+	if err != nil {
+		return TimeZone{}, err
+	}
+
+	if err := p.quota.take(); err != nil {
+		return TimeZone{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return TimeZone{}, err
+	}
+
+	resp, err := p.do(req)
+	if err != nil {
+		return TimeZone{}, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return TimeZone{}, &UpstreamStatusError{API: "google time zone api", StatusCode: resp.StatusCode}
+	}
+
+	var payload timezoneResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return TimeZone{}, err
+	}
+
+	switch payload.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return TimeZone{}, ErrNoResults
+	default:
+		return TimeZone{}, &APIStatusError{API: "google time zone api", Status: payload.Status, Message: payload.ErrorMessage}
+	}
+
+	return TimeZone{
+		TimeZoneID:   payload.TimeZoneID,
+		TimeZoneName: payload.TimeZoneName,
+		RawOffset:    payload.RawOffset,
+		DSTOffset:    payload.DSTOffset,
+		Source:       p.Name(),
+	}, nil
+}
+
+// timezoneResponse models the Google Time Zone API response.
+type timezoneResponse struct {
+	DSTOffset    int    `json:"dstOffset"`
+	RawOffset    int    `json:"rawOffset"`
+	TimeZoneID   string `json:"timeZoneId"`
+	TimeZoneName string `json:"timeZoneName"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// timezoneCache is a TTL cache for time zone lookups. Expired entries are dropped when read and
+// swept as the cache grows.
+type timezoneCache struct {
+	ttl   time.Duration
+	now   func() time.Time
+	items map[string]timezoneItem
+	mu    sync.RWMutex
+	sweep sweepSchedule
+}
+
+type timezoneItem struct {
+	value   TimeZone
+	expires time.Time
+}
+
+func newTimezoneCache(ttl time.Duration, now func() time.Time) *timezoneCache {
+	return &timezoneCache{
+		ttl:   ttl,
+		now:   now,
+		items: make(map[string]timezoneItem),
+	}
+}
+
+func (c *timezoneCache) Get(key string) (TimeZone, bool) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok {
+		return TimeZone{}, false
+	}
+	if c.now().After(item.expires) {
+		c.mu.Lock()
+		delete(c.items, key)
+		c.mu.Unlock()
+		return TimeZone{}, false
+	}
+	return item.value, true
+}
+
+func (c *timezoneCache) Set(key string, value TimeZone) {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sweep.due(len(c.items)) {
+		for k, item := range c.items {
+			if now.After(item.expires) {
+				delete(c.items, k)
+			}
+		}
+		c.sweep.swept(len(c.items))
+	}
+	c.items[key] = timezoneItem{
+		value:   value,
+		expires: now.Add(c.ttl),
+	}
+}
@@ -0,0 +1,128 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// losAngelesTimeZone is the Time Zone API response documented by Google for Los Angeles in winter.
+const losAngelesTimeZone = `{
+  "dstOffset": 0,
+  "rawOffset": -28800,
+  "status": "OK",
+  "timeZoneId": "America/Los_Angeles",
+  "timeZoneName": "Pacific Standard Time"
+}`
+
+func TestTimeZone(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Path+"?location="+r.URL.Query().Get("location")+"&timestamp="+r.URL.Query().Get("timestamp"))
+		mu.Unlock()
+		respond(http.StatusOK, losAngelesTimeZone)(w, r)
+	})
+	s := newTestService(t, google.option())
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tz, err := s.TimeZone(context.Background(), 34.0522, -118.2437, at)
+	if err != nil {
+		t.Fatalf("TimeZone: %v", err)
+	}
+	want := TimeZone{
+		TimeZoneID:   "America/Los_Angeles",
+		TimeZoneName: "Pacific Standard Time",
+		RawOffset:    -28800,
+		DSTOffset:    0,
+		Source:       "google",
+	}
+	if tz != want {
+		t.Fatalf("TimeZone = %+v, want %+v", tz, want)
+	}
+
+	// The same coordinate within the hour, rounded to the same key, is answered from the cache;
+	// another hour is looked up again.
+	tz, err = s.TimeZone(context.Background(), 34.05221, -118.24369, at.Add(20*time.Minute))
+	if err != nil || tz.Source != "cache" {
+		t.Fatalf("TimeZone within the hour = %+v, %v; want a cache hit", tz, err)
+	}
+	if _, err := s.TimeZone(context.Background(), 34.0522, -118.2437, at.Add(time.Hour)); err != nil {
+		t.Fatalf("TimeZone an hour later: %v", err)
+	}
+
+	hour := at.Truncate(time.Hour).Unix()
+	wantQueries := []string{
+		"/maps/api/timezone/json?location=34.0522,-118.2437&timestamp=" + strconv.FormatInt(hour, 10),
+		"/maps/api/timezone/json?location=34.0522,-118.2437&timestamp=" + strconv.FormatInt(hour+3600, 10),
+	}
+	if len(queries) != len(wantQueries) {
+		t.Fatalf("queries = %q, want %q", queries, wantQueries)
+	}
+	for i := range wantQueries {
+		if queries[i] != wantQueries[i] {
+			t.Errorf("query %d = %q, want %q", i, queries[i], wantQueries[i])
+		}
+	}
+}
+
+func TestTimeZoneErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		body     string
+		wantErr  error
+		wantCall bool
+	}{
+		{name: "latitude out of range", lat: 91, lng: 0, wantErr: ErrInvalidCoordinates},
+		{name: "longitude out of range", lat: 0, lng: -181, wantErr: ErrInvalidCoordinates},
+		{name: "NaN latitude", lat: math.NaN(), lng: 0, wantErr: ErrInvalidCoordinates},
+		{name: "NaN longitude", lat: 0, lng: math.NaN(), wantErr: ErrInvalidCoordinates},
+		{name: "zero results", lat: 0, lng: -150, body: `{"status": "ZERO_RESULTS"}`, wantErr: ErrNoResults, wantCall: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, tt.body))
+			s := newTestService(t, google.option())
+
+			if _, err := s.TimeZone(context.Background(), tt.lat, tt.lng, time.Now()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TimeZone = %v, want %v", err, tt.wantErr)
+			}
+			if called := google.requests.Load() > 0; called != tt.wantCall {
+				t.Fatalf("upstream called = %v, want %v", called, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestTimeZoneAPIStatusError(t *testing.T) {
+	google := newFakeGoogle(t, respond(http.StatusOK, `{"status": "REQUEST_DENIED", "errorMessage": "The provided API key is invalid."}`))
+	s := newTestService(t, google.option())
+
+	_, err := s.TimeZone(context.Background(), 34.0522, -118.2437, time.Now())
+	var apiErr *APIStatusError
+	if !errors.As(err, &apiErr) || apiErr.Status != "REQUEST_DENIED" {
+		t.Fatalf("TimeZone = %v, want a REQUEST_DENIED *APIStatusError", err)
+	}
+}
+
+func TestTimezoneCacheSweepsExpiredEntries(t *testing.T) {
+	clock := newFakeClock()
+	c := newTimezoneCache(time.Minute, clock.Now)
+	for i := 0; i < minSweepSize; i++ {
+		c.Set(strconv.Itoa(i), TimeZone{TimeZoneID: "UTC"})
+	}
+
+	clock.Advance(2 * time.Minute)
+	c.Set("fresh", TimeZone{TimeZoneID: "UTC"})
+
+	if got := len(c.items); got != 1 {
+		t.Fatalf("time zone cache holds %d entries after a sweep, want 1", got)
+	}
+}
@@ -0,0 +1,33 @@
+package geocode
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type upstreamTimerKey struct{}
+
+// UpstreamTimer accumulates the time spent calling providers on behalf of a request. Requests served
+// from the cache, or that joined a lookup started by another request, record nothing.
+type UpstreamTimer struct {
+	nanos atomic.Int64
+}
+
+// WithUpstreamTimer returns a context whose lookups record their upstream time in the returned timer.
+func WithUpstreamTimer(ctx context.Context) (context.Context, *UpstreamTimer) {
+	timer := &UpstreamTimer{}
+	return context.WithValue(ctx, upstreamTimerKey{}, timer), timer
+}
+
+// Duration returns the upstream time recorded so far.
+func (t *UpstreamTimer) Duration() time.Duration {
+	return time.Duration(t.nanos.Load())
+}
+
+// recordUpstream adds the time elapsed since start to the timer carried by ctx, if any.
+func recordUpstream(ctx context.Context, start time.Time) {
+	if timer, ok := ctx.Value(upstreamTimerKey{}).(*UpstreamTimer); ok {
+		timer.nanos.Add(int64(time.Since(start)))
+	}
+}
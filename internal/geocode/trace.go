@@ -0,0 +1,99 @@
+package geocode
+
+import (
+	"context"
+	"sync"
+)
+
+type traceKey struct{}
+
+// Trace records the decisions taken while serving a request: whether the cache answered, which
+// provider produced the result, whether a provider other than the first one in the chain (or the
+// coordinate fallback) answered, and how many provider attempts failed with an error first. Requests that joined a lookup started by another request see the provider but not the
+// fallback or retries, which belong to the request that ran the lookup.
+type Trace struct {
+	mu     sync.Mutex
+	values TraceValues
+}
+
+// TraceValues is a copy of the decisions recorded in a Trace.
+type TraceValues struct {
+	// Traced is false until a lookup records anything, so requests that never geocode can be told
+	// apart from cache misses.
+	Traced       bool
+	CacheHit     bool
+	Provider     string
+	FallbackUsed bool
+	Retries      int
+	// Attempts lists the provider calls made by this request's lookups, in order.
+	Attempts []TraceAttempt
+}
+
+// TraceAttempt is the outcome of one provider call: ok, no_results, timeout, canceled or error.
+type TraceAttempt struct {
+	Provider string `json:"provider"`
+	Outcome  string `json:"outcome"`
+	Error    string `json:"error,omitempty"`
+}
+
+// WithTrace returns a context whose lookups record their decisions in the returned trace.
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+// Values returns the decisions recorded so far. When a request performs several lookups, the last
+// one decides CacheHit and Provider and retries add up.
+func (t *Trace) Values() TraceValues {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	values := t.values
+	values.Attempts = append([]TraceAttempt(nil), t.values.Attempts...)
+	return values
+}
+
+// TraceFromContext returns the trace carried by ctx, or nil. All Trace recording methods accept nil.
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// attempted records a call to provider that ended with err.
+func (t *Trace) attempted(provider string, err error) {
+	if t == nil {
+		return
+	}
+	attempt := TraceAttempt{Provider: provider, Outcome: outcome(err)}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.values.Traced = true
+	t.values.Attempts = append(t.values.Attempts, attempt)
+}
+
+// answered records that provider produced the result, from the cache when cacheHit is set.
+func (t *Trace) answered(provider string, cacheHit bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.values.Traced = true
+	t.values.CacheHit = cacheHit
+	t.values.Provider = provider
+}
+
+// fellBack records that the answer did not come from the first provider tried, after retries
+// failed attempts.
+func (t *Trace) fellBack(retries int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.values.Traced = true
+	t.values.FallbackUsed = true
+	t.values.Retries += retries
+}
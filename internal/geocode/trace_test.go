@@ -0,0 +1,53 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTraceRecordsLookupDecisions(t *testing.T) {
+	noResults := func(context.Context, string) (Result, error) { return Result{}, ErrNoResults }
+	failure := func(context.Context, string) (Result, error) { return Result{}, errors.New("connection reset") }
+	answer := answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "second"})
+
+	tests := []struct {
+		name   string
+		first  func(context.Context, string) (Result, error)
+		cached bool
+		want   TraceValues
+	}{
+		{name: "first provider answers", first: answerWith(Result{Latitude: -23.55, Longitude: -46.63, Source: "first"}),
+			want: TraceValues{Traced: true, Provider: "first"}},
+		{name: "fallback after no results", first: noResults,
+			want: TraceValues{Traced: true, Provider: "second", FallbackUsed: true}},
+		{name: "fallback after an error", first: failure,
+			want: TraceValues{Traced: true, Provider: "second", FallbackUsed: true, Retries: 1}},
+		{name: "cache hit", first: noResults, cached: true,
+			want: TraceValues{Traced: true, CacheHit: true, Provider: "second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, WithProviders(
+				&stubProvider{name: "first", answer: tt.first},
+				&stubProvider{name: "second", answer: answer},
+			))
+			if tt.cached {
+				if _, err := s.Geocode(context.Background(), "Praça da Sé"); err != nil {
+					t.Fatalf("Geocode: %v", err)
+				}
+			}
+
+			ctx, trace := WithTrace(context.Background())
+			if _, err := s.Geocode(ctx, "Praça da Sé"); err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			got := trace.Values()
+			got.Attempts = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trace = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
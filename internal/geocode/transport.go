@@ -0,0 +1,38 @@
+package geocode
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportSettings tunes the connections kept open to the Google Maps APIs.
+type TransportSettings struct {
+	// IdleConnTimeout closes connections that stayed idle this long. Keeping it below the time
+	// Google's servers keep idle connections open means the client closes a connection before the
+	// server does, instead of discovering the reset on the next request.
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost bounds the idle connections kept per host.
+	MaxIdleConnsPerHost int
+	// KeepAlive is the interval between TCP keep-alive probes on open connections.
+	KeepAlive time.Duration
+}
+
+// DefaultTransportSettings are used when WithTransportSettings is not given.
+var DefaultTransportSettings = TransportSettings{
+	IdleConnTimeout:     30 * time.Second,
+	MaxIdleConnsPerHost: 10,
+	KeepAlive:           30 * time.Second,
+}
+
+// newTransport builds a transport from the defaults of http.DefaultTransport with settings applied.
+func newTransport(settings TransportSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.IdleConnTimeout = settings.IdleConnTimeout
+	transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: settings.KeepAlive,
+	}).DialContext
+	return transport
+}
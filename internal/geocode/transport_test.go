@@ -0,0 +1,156 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewTransportAppliesSettings(t *testing.T) {
+	settings := TransportSettings{IdleConnTimeout: 7 * time.Second, MaxIdleConnsPerHost: 3, KeepAlive: 11 * time.Second}
+	s := newTestService(t, WithTransportSettings(settings))
+
+	transport, ok := s.google.client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport = %T, want *http.Transport", s.google.client.Transport)
+	}
+	if transport.IdleConnTimeout != 7*time.Second || transport.MaxIdleConnsPerHost != 3 {
+		t.Errorf("idle timeout %s with %d idle conns per host, want 7s with 3", transport.IdleConnTimeout, transport.MaxIdleConnsPerHost)
+	}
+	if transport.DialContext == nil || transport.Proxy == nil {
+		t.Error("the transport lost the defaults of http.DefaultTransport")
+	}
+}
+
+func TestIdleConnectionsAreClosedAfterTheTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		idle      time.Duration
+		wantConns int32
+	}{
+		{name: "reused within the timeout", idle: time.Minute, wantConns: 1},
+		{name: "closed after the timeout", idle: 20 * time.Millisecond, wantConns: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			google := newFakeGoogle(t, respond(http.StatusOK, sePayload))
+			settings := DefaultTransportSettings
+			settings.IdleConnTimeout = tt.idle
+			s := newTestService(t, google.option(), WithTransportSettings(settings))
+
+			for _, address := range []string{"Praça da Sé", "Avenida Paulista"} {
+				if _, err := s.Geocode(context.Background(), address); err != nil {
+					t.Fatalf("Geocode: %v", err)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			if got := google.conns.Load(); got != tt.wantConns {
+				t.Errorf("opened %d connections, want %d", got, tt.wantConns)
+			}
+		})
+	}
+}
+
+func TestWithTransportSettingsRejectsNegativeValues(t *testing.T) {
+	for _, settings := range []TransportSettings{
+		{IdleConnTimeout: -time.Second},
+		{MaxIdleConnsPerHost: -1},
+		{KeepAlive: -time.Second},
+	} {
+		if _, err := New(WithTransportSettings(settings)); err == nil {
+			t.Errorf("New accepted %+v", settings)
+		}
+	}
+}
+
+func TestWithRoundTripperWrapsOutboundCalls(t *testing.T) {
+	// tagging returns a wrapper that records its name and sets a header before passing the request on.
+	tagging := func(name string, calls *[]string) Option {
+		return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				*calls = append(*calls, name)
+				req = req.Clone(req.Context())
+				req.Header.Add("X-Trace", name)
+				return next.RoundTrip(req)
+			})
+		})
+	}
+	replace := WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			rec := httptest.NewRecorder()
+			respond(http.StatusOK, sePayload)(rec, req)
+			return rec.Result(), nil
+		})
+	})
+
+	tests := []struct {
+		name         string
+		wrappers     func(google *fakeGoogle, calls *[]string) []Option
+		wantCalls    []string
+		wantHeaders  []string
+		wantUpstream int32
+	}{
+		{
+			name: "single wrapper",
+			wrappers: func(google *fakeGoogle, calls *[]string) []Option {
+				return []Option{google.option(), tagging("tracing", calls)}
+			},
+			wantCalls:    []string{"tracing"},
+			wantHeaders:  []string{"tracing"},
+			wantUpstream: 1,
+		},
+		{
+			name: "later wrappers run first",
+			wrappers: func(google *fakeGoogle, calls *[]string) []Option {
+				return []Option{google.option(), tagging("inner", calls), tagging("outer", calls)}
+			},
+			wantCalls:    []string{"outer", "inner"},
+			wantHeaders:  []string{"outer", "inner"},
+			wantUpstream: 1,
+		},
+		{
+			name: "replaced transport",
+			wrappers: func(google *fakeGoogle, calls *[]string) []Option {
+				return []Option{replace, tagging("tracing", calls)}
+			},
+			wantCalls: []string{"tracing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			google := newFakeGoogle(t, func(w http.ResponseWriter, r *http.Request) {
+				headers = r.Header.Values("X-Trace")
+				respond(http.StatusOK, sePayload)(w, r)
+			})
+			var calls []string
+			s := newTestService(t, tt.wrappers(google, &calls)...)
+
+			result, err := s.Geocode(context.Background(), "Praça da Sé")
+			if err != nil {
+				t.Fatalf("Geocode: %v", err)
+			}
+			if result.Source != "google" {
+				t.Errorf("source = %s, want google", result.Source)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("wrapper calls = %q, want %q", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(headers, tt.wantHeaders) {
+				t.Errorf("upstream X-Trace = %q, want %q", headers, tt.wantHeaders)
+			}
+			if got := google.requests.Load(); got != tt.wantUpstream {
+				t.Errorf("upstream requests = %d, want %d", got, tt.wantUpstream)
+			}
+		})
+	}
+}
+
+func TestWithRoundTripperRejectsNil(t *testing.T) {
+	if _, err := New(WithRoundTripper(nil)); err == nil {
+		t.Fatal("expected an error for a nil wrapper")
+	}
+}
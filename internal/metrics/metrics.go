@@ -0,0 +1,37 @@
+// Package metrics defines the sink the service reports counters and timings to.
+package metrics
+
+import "time"
+
+// Sink receives metric events. Tags are "key:value" pairs; sinks that do not support tags ignore
+// them. Implementations must be safe for concurrent use and must not block the caller.
+type Sink interface {
+	Count(name string, value int64, tags ...string)
+	Timing(name string, d time.Duration, tags ...string)
+}
+
+// Nop discards every event. It is used when no sink is configured.
+type Nop struct{}
+
+// Count implements Sink.
+func (Nop) Count(string, int64, ...string) {}
+
+// Timing implements Sink.
+func (Nop) Timing(string, time.Duration, ...string) {}
+
+// Multi forwards every event to each of its sinks.
+type Multi []Sink
+
+// Count implements Sink.
+func (m Multi) Count(name string, value int64, tags ...string) {
+	for _, sink := range m {
+		sink.Count(name, value, tags...)
+	}
+}
+
+// Timing implements Sink.
+func (m Multi) Timing(name string, d time.Duration, tags ...string) {
+	for _, sink := range m {
+		sink.Timing(name, d, tags...)
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsD sends metrics over UDP in the StatsD line format. With tags enabled it appends them in
+// the DogStatsD "|#key:value" extension. Send errors are ignored: metrics must never affect
+// request handling.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+// NewStatsD creates a sink sending to addr (host:port). Every metric name is prefixed with prefix
+// and a dot, unless prefix is empty.
+func NewStatsD(addr, prefix string, tags bool) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefix += "."
+	}
+	return &StatsD{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+// Count implements Sink.
+func (s *StatsD) Count(name string, value int64, tags ...string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing implements Sink. Durations are reported in milliseconds.
+func (s *StatsD) Timing(name string, d time.Duration, tags ...string) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	s.send(name, ms, "ms", tags)
+}
+
+// Close releases the underlying connection.
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsD) send(name, value, kind string, tags []string) {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if s.tags && len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(tags, ","))
+	}
+	_, _ = s.conn.Write([]byte(b.String()))
+}
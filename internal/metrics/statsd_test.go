@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenUDP opens a local UDP listener and returns it with its address.
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readLine reads the next datagram received by conn.
+func readLine(t *testing.T, conn *net.UDPConn) string {
+	t.Helper()
+	buf := make([]byte, 1024)
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestStatsD(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		tags   bool
+		emit   func(Sink)
+		want   string
+	}{
+		{
+			name: "counter",
+			emit: func(s Sink) { s.Count("cache.hit", 1) },
+			want: "cache.hit:1|c",
+		},
+		{
+			name:   "prefixed counter",
+			prefix: "apigo",
+			emit:   func(s Sink) { s.Count("requests", 3, "route:/geocode") },
+			want:   "apigo.requests:3|c",
+		},
+		{
+			name:   "tagged counter",
+			prefix: "apigo",
+			tags:   true,
+			emit:   func(s Sink) { s.Count("requests", 1, "route:/geocode", "status:200") },
+			want:   "apigo.requests:1|c|#route:/geocode,status:200",
+		},
+		{
+			name:   "upstream error counter",
+			prefix: "apigo",
+			tags:   true,
+			emit:   func(s Sink) { s.Count("upstream.errors", 1, "provider:google", "category:quota") },
+			want:   "apigo.upstream.errors:1|c|#provider:google,category:quota",
+		},
+		{
+			name: "timing in milliseconds",
+			emit: func(s Sink) { s.Timing("upstream.duration", 1500*time.Microsecond) },
+			want: "upstream.duration:1.500|ms",
+		},
+		{
+			name: "tagged timing",
+			tags: true,
+			emit: func(s Sink) { s.Timing("upstream.duration", 42*time.Millisecond, "provider:google", "outcome:ok") },
+			want: "upstream.duration:42.000|ms|#provider:google,outcome:ok",
+		},
+		{
+			name: "tags enabled without tags",
+			tags: true,
+			emit: func(s Sink) { s.Count("cache.miss", 1) },
+			want: "cache.miss:1|c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := listenUDP(t)
+			sink, err := NewStatsD(listener.LocalAddr().String(), tt.prefix, tt.tags)
+			if err != nil {
+				t.Fatalf("NewStatsD: %v", err)
+			}
+			t.Cleanup(func() { sink.Close() })
+
+			tt.emit(sink)
+			if got := readLine(t, listener); got != tt.want {
+				t.Errorf("line = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiForwardsToEverySink(t *testing.T) {
+	first, second := listenUDP(t), listenUDP(t)
+	var sinks Multi
+	for _, listener := range []*net.UDPConn{first, second} {
+		sink, err := NewStatsD(listener.LocalAddr().String(), "apigo", false)
+		if err != nil {
+			t.Fatalf("NewStatsD: %v", err)
+		}
+		t.Cleanup(func() { sink.Close() })
+		sinks = append(sinks, sink)
+	}
+	sinks = append(sinks, Nop{})
+
+	sinks.Count("errors", 1)
+	for _, listener := range []*net.UDPConn{first, second} {
+		if got := readLine(t, listener); got != "apigo.errors:1|c" {
+			t.Errorf("line = %q, want apigo.errors:1|c", got)
+		}
+	}
+}
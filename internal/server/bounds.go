@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"apigo/internal/geocode"
+)
+
+// Limits for POST /bounds. Its deadline comes from the route timeout, which must stay below the
+// HTTP server's write timeout so partial results are still delivered when some lookups are slow.
+const (
+	maxBoundsAddresses = 100
+	boundsWorkers      = 8
+	maxBoundsBodyBytes = 1 << 20
+)
+
+type boundsRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+type boundsFailure struct {
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+type boundsResponse struct {
+	Bounds   *geocode.Bounds `json:"bounds"`
+	Resolved int             `json:"resolved"`
+	Failed   []boundsFailure `json:"failed"`
+}
+
+func boundsHandler(service *geocode.Service, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxBoundsBodyBytes)
+		var req boundsRequest
+		if isPlainText(r) {
+			addresses, err := readAddressLines(body)
+			if err != nil {
+				rs.error(w, r, http.StatusBadRequest, "unable to read request body")
+				return
+			}
+			req.Addresses = addresses
+		} else if err := json.NewDecoder(body).Decode(&req); err != nil {
+			rs.error(w, r, http.StatusBadRequest, "request body must be a JSON object with an addresses array")
+			return
+		}
+		if len(req.Addresses) == 0 {
+			rs.error(w, r, http.StatusBadRequest, "addresses must not be empty")
+			return
+		}
+		if len(req.Addresses) > maxBoundsAddresses {
+			rs.error(w, r, http.StatusBadRequest, "too many addresses")
+			return
+		}
+
+		results, errs := geocodeAll(r.Context(), service, req.Addresses)
+
+		bounds := geocode.NewBounds()
+		resp := boundsResponse{Failed: []boundsFailure{}}
+		for i, address := range req.Addresses {
+			if errs[i] != nil {
+				resp.Failed = append(resp.Failed, boundsFailure{Address: address, Error: errs[i].Error()})
+				continue
+			}
+			bounds.Extend(results[i].Latitude, results[i].Longitude)
+			resp.Resolved++
+		}
+		if !bounds.Empty() {
+			resp.Bounds = &bounds
+		}
+
+		rs.json(w, r, http.StatusOK, resp)
+	}
+}
+
+// isPlainText reports whether the request body is text/plain rather than JSON.
+func isPlainText(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/plain"
+}
+
+// readAddressLines reads one address per line, skipping blank lines.
+func readAddressLines(body io.Reader) ([]string, error) {
+	var addresses []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			addresses = append(addresses, line)
+		}
+	}
+	return addresses, scanner.Err()
+}
+
+// geocodeAll resolves addresses with a bounded number of concurrent lookups. Results and errors
+// are returned in input order.
+func geocodeAll(ctx context.Context, service *geocode.Service, addresses []string) ([]geocode.Result, []error) {
+	results := make([]geocode.Result, len(addresses))
+	errs := make([]error, len(addresses))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(boundsWorkers, len(addresses)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = service.Geocode(ctx, strings.TrimSpace(addresses[i]))
+			}
+		}()
+	}
+	for i := range addresses {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, errs
+}
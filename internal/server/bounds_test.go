@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cities answers Geocoding API requests for a few known addresses and ZERO_RESULTS otherwise.
+func cities(w http.ResponseWriter, r *http.Request) {
+	coordinates := map[string][2]float64{
+		"são paulo":      {-23.5505, -46.6333},
+		"rio de janeiro": {-22.9068, -43.1729},
+		"brasília":       {-15.7939, -47.8828},
+	}
+	c, ok := coordinates[r.URL.Query().Get("address")]
+	if !ok {
+		respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`)(w, r)
+		return
+	}
+	respond(http.StatusOK, fmt.Sprintf(`{"status": "OK", "results": [{
+		"formatted_address": %q,
+		"geometry": {"location": {"lat": %v, "lng": %v}, "location_type": "APPROXIMATE"}
+	}]}`, r.URL.Query().Get("address"), c[0], c[1]))(w, r)
+}
+
+func TestBoundsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		want        map[string]any
+	}{
+		{
+			name:       "all resolved",
+			body:       `{"addresses": ["São Paulo", "Rio de Janeiro", "Brasília"]}`,
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"bounds": map[string]any{
+					"southwest": map[string]any{"latitude": -23.5505, "longitude": -47.8828},
+					"northeast": map[string]any{"latitude": -15.7939, "longitude": -43.1729},
+				},
+				"resolved": float64(3),
+				"failed":   []any{},
+			},
+		},
+		{
+			name:       "some failed",
+			body:       `{"addresses": ["São Paulo", "Atlantis", "Rio de Janeiro"]}`,
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"bounds": map[string]any{
+					"southwest": map[string]any{"latitude": -23.5505, "longitude": -46.6333},
+					"northeast": map[string]any{"latitude": -22.9068, "longitude": -43.1729},
+				},
+				"resolved": float64(2),
+				"failed":   []any{map[string]any{"address": "Atlantis", "error": "all providers failed: google: no results found"}},
+			},
+		},
+		{
+			name:       "none resolved",
+			body:       `{"addresses": ["Atlantis"]}`,
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"bounds":   nil,
+				"resolved": float64(0),
+				"failed":   []any{map[string]any{"address": "Atlantis", "error": "all providers failed: google: no results found"}},
+			},
+		},
+		{name: "empty", body: `{"addresses": []}`, wantStatus: http.StatusBadRequest, want: map[string]any{"error": "addresses must not be empty"}},
+		{name: "malformed", body: `["São Paulo"]`, wantStatus: http.StatusBadRequest, want: map[string]any{"error": "request body must be a JSON object with an addresses array"}},
+		{
+			name:        "plain text with blank lines",
+			contentType: "text/plain",
+			body:        "São Paulo\n\n   \nAtlantis\r\nRio de Janeiro\n\n",
+			wantStatus:  http.StatusOK,
+			want: map[string]any{
+				"bounds": map[string]any{
+					"southwest": map[string]any{"latitude": -23.5505, "longitude": -46.6333},
+					"northeast": map[string]any{"latitude": -22.9068, "longitude": -43.1729},
+				},
+				"resolved": float64(2),
+				"failed":   []any{map[string]any{"address": "Atlantis", "error": "all providers failed: google: no results found"}},
+			},
+		},
+		{
+			name:        "plain text with a charset",
+			contentType: "text/plain; charset=utf-8",
+			body:        "Brasília",
+			wantStatus:  http.StatusOK,
+			want: map[string]any{
+				"bounds": map[string]any{
+					"southwest": map[string]any{"latitude": -15.7939, "longitude": -47.8828},
+					"northeast": map[string]any{"latitude": -15.7939, "longitude": -47.8828},
+				},
+				"resolved": float64(1),
+				"failed":   []any{},
+			},
+		},
+		{name: "plain text only blank lines", contentType: "text/plain", body: "\n \n\t\n", wantStatus: http.StatusBadRequest,
+			want: map[string]any{"error": "addresses must not be empty"}},
+		{name: "plain text over the limit", contentType: "text/plain", body: strings.Repeat("São Paulo\n", maxBoundsAddresses+1), wantStatus: http.StatusBadRequest,
+			want: map[string]any{"error": "too many addresses"}},
+		{name: "plain text at the limit", contentType: "text/plain", body: strings.Repeat("São Paulo\n\n", maxBoundsAddresses), wantStatus: http.StatusOK,
+			want: map[string]any{
+				"bounds": map[string]any{
+					"southwest": map[string]any{"latitude": -23.5505, "longitude": -46.6333},
+					"northeast": map[string]any{"latitude": -23.5505, "longitude": -46.6333},
+				},
+				"resolved": float64(maxBoundsAddresses),
+				"failed":   []any{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, newTestService(t, cities), Options{})
+			req := httptest.NewRequest(http.MethodPost, "/bounds", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := decode(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
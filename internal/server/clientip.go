@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIP resolves the address of the client behind any trusted proxies and stores it in the
+// request context; see ClientIPFromContext. trustedProxies holds CIDRs or single addresses.
+//
+// X-Forwarded-For is only honored when the direct peer is trusted. Entries are then walked from the
+// right, skipping trusted proxies, and the first untrusted address is the client. Walking from the
+// left would let any client pick its own address by sending a forged header.
+func ClientIP(trustedProxies []string) (Middleware, error) {
+	trusted := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, raw := range trustedProxies {
+		prefix, err := parsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}, nil
+}
+
+// ClientIPFromContext returns the address resolved by ClientIP, or an empty string.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := parseHost(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	client := peer
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && isTrusted(client, trusted); i-- {
+		hop, ok := parseHost(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		client = hop
+	}
+	return client.String()
+}
+
+// parseHost parses an address with or without a port, including bracketed IPv6 notation.
+func parseHost(raw string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(raw, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func parsePrefix(raw string) (netip.Prefix, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.Contains(raw, "/") {
+		prefix, err := netip.ParsePrefix(raw)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8:ffff::/48", "192.0.2.1"}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{name: "IPv4 peer", remoteAddr: "203.0.113.7:52100", want: "203.0.113.7"},
+		{name: "IPv6 peer", remoteAddr: "[2001:db8::7]:52100", want: "2001:db8::7"},
+		{name: "IPv4-mapped IPv6 peer", remoteAddr: "[::ffff:203.0.113.7]:52100", want: "203.0.113.7"},
+		{name: "peer without a port", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+		{name: "unparseable peer", remoteAddr: "pipe", want: "pipe"},
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:443", xff: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "trusted single address", remoteAddr: "192.0.2.1:443", xff: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "IPv6 client behind a trusted proxy", remoteAddr: "10.1.2.3:443", xff: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "bracketed IPv6 hop with a port", remoteAddr: "10.1.2.3:443", xff: []string{"[2001:db8::7]:52100"}, want: "2001:db8::7"},
+		{name: "chain of trusted proxies", remoteAddr: "10.1.2.3:443", xff: []string{"203.0.113.7, 10.9.9.9, [2001:db8:ffff::1]"}, want: "203.0.113.7"},
+		{name: "chain split over headers", remoteAddr: "10.1.2.3:443", xff: []string{"203.0.113.7", "10.9.9.9"}, want: "203.0.113.7"},
+		{name: "spoofed entry left of the client", remoteAddr: "10.1.2.3:443", xff: []string{"198.51.100.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "untrusted peer sending XFF", remoteAddr: "198.51.100.9:52100", xff: []string{"203.0.113.7"}, want: "198.51.100.9"},
+		{name: "untrusted peer claiming a trusted proxy", remoteAddr: "198.51.100.9:52100", xff: []string{"203.0.113.7, 10.1.2.3"}, want: "198.51.100.9"},
+		{name: "garbage hop stops the walk", remoteAddr: "10.1.2.3:443", xff: []string{"203.0.113.7, not-an-ip"}, want: "10.1.2.3"},
+		{name: "only trusted hops", remoteAddr: "10.1.2.3:443", xff: []string{"10.9.9.9"}, want: "10.9.9.9"},
+	}
+
+	middleware, err := ClientIP(trusted)
+	if err != nil {
+		t.Fatalf("ClientIP: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = ClientIPFromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPRejectsInvalidTrustedProxies(t *testing.T) {
+	for _, raw := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0"} {
+		if _, err := ClientIP([]string{raw}); err == nil {
+			t.Errorf("ClientIP accepted %q", raw)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed static/index.html
+var demoPage []byte
+
+// demoHandler serves the embedded demo page at the root path only, so unknown paths still 404.
+func demoHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(demoPage)
+	}
+}
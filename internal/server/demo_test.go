@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDemoPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "disabled", method: http.MethodGet, target: "/", wantStatus: http.StatusNotFound},
+		{name: "enabled", enabled: true, method: http.MethodGet, target: "/", wantStatus: http.StatusOK},
+		{name: "enabled, unknown path", enabled: true, method: http.MethodGet, target: "/unknown", wantStatus: http.StatusNotFound},
+		{name: "enabled, wrong method", enabled: true, method: http.MethodPost, target: "/", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			rec := serve(t, service, Options{DemoPage: tt.enabled}, tt.method, tt.target)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if !strings.Contains(rec.Body.String(), "/geocode") {
+				t.Error("the demo page does not call /geocode")
+			}
+		})
+	}
+}
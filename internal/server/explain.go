@@ -0,0 +1,36 @@
+package server
+
+import "apigo/internal/geocode"
+
+// explanation describes how a /geocode answer was produced. It is only built for explain=true when
+// Options.Explain is set, and only presents data recorded by the service in a geocode.Trace.
+type explanation struct {
+	// Normalization is omitted for postal code lookups, which build a components filter instead.
+	Normalization *geocode.Normalization `json:"normalization,omitempty"`
+	CacheHit      bool                   `json:"cache_hit"`
+	Provider      string                 `json:"provider,omitempty"`
+	FallbackUsed  bool                   `json:"fallback_used"`
+	Retries       int                    `json:"retries"`
+	// Attempts lists the provider calls made for this request. It is empty for cache hits and for
+	// requests that joined a lookup already in flight.
+	Attempts []geocode.TraceAttempt `json:"attempts"`
+	// Selection is the tie-break strategy used when Google returns several results.
+	Selection string `json:"selection"`
+}
+
+func newExplanation(service *geocode.Service, trace *geocode.Trace, normalization *geocode.Normalization) *explanation {
+	values := trace.Values()
+	attempts := values.Attempts
+	if attempts == nil {
+		attempts = []geocode.TraceAttempt{}
+	}
+	return &explanation{
+		Normalization: normalization,
+		CacheHit:      values.CacheHit,
+		Provider:      values.Provider,
+		FallbackUsed:  values.FallbackUsed,
+		Retries:       values.Retries,
+		Attempts:      attempts,
+		Selection:     service.TieBreak(),
+	}
+}
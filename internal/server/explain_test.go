@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"apigo/internal/geocode"
+)
+
+func TestGeocodeExplain(t *testing.T) {
+	normalization := map[string]any{"raw": "Praça da Sé", "preprocessed": "Praça da Sé", "key": "praça da sé"}
+	miss := map[string]any{
+		"normalization": normalization,
+		"cache_hit":     false,
+		"provider":      "google",
+		"fallback_used": false,
+		"retries":       float64(0),
+		"attempts":      []any{map[string]any{"provider": "google", "outcome": "ok"}},
+		"selection":     "first",
+	}
+	hit := map[string]any{
+		"normalization": normalization,
+		"cache_hit":     true,
+		"provider":      "google",
+		"fallback_used": false,
+		"retries":       float64(0),
+		"attempts":      []any{},
+		"selection":     "first",
+	}
+	fallback := map[string]any{
+		"normalization": normalization,
+		"cache_hit":     false,
+		"provider":      "google",
+		"fallback_used": true,
+		"retries":       float64(0),
+		"attempts": []any{
+			map[string]any{"provider": "static", "outcome": "no_results", "error": "no results found"},
+			map[string]any{"provider": "google", "outcome": "ok"},
+		},
+		"selection": "first",
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		service  []geocode.Option
+		requests []string
+		want     []any
+	}{
+		{
+			name:     "miss then hit",
+			opts:     Options{Explain: true},
+			requests: []string{"explain=true", "explain=true"},
+			want:     []any{miss, hit},
+		},
+		{
+			name:     "fallback",
+			opts:     Options{Explain: true},
+			service:  []geocode.Option{geocode.WithProviders(geocode.NewStaticProvider(nil))},
+			requests: []string{"explain=true"},
+			want:     []any{fallback},
+		},
+		{
+			name:     "not requested",
+			opts:     Options{Explain: true},
+			requests: []string{""},
+			want:     []any{nil},
+		},
+		{
+			name:     "not allowed",
+			requests: []string{"explain=true"},
+			want:     []any{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload), tt.service...)
+			for i, params := range tt.requests {
+				rec := serve(t, service, tt.opts, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9&"+params)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+				}
+				if got := decode(t, rec)["_explain"]; !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("request %d: _explain = %v\nwant %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// cacheGraceHandler starts a cache grace period from the factor and duration query parameters, for
+// example ahead of a provider's maintenance window. Callers must send the configured token as
+// "Authorization: Bearer <token>". Each change is logged with the caller's IP and request ID.
+func cacheGraceHandler(service *geocode.Service, token string, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if !rs.authorize(w, r, token) {
+			return
+		}
+
+		query := r.URL.Query()
+		factor, factorErr := strconv.ParseFloat(query.Get("factor"), 64)
+		duration, durationErr := time.ParseDuration(query.Get("duration"))
+		if factorErr != nil || durationErr != nil {
+			rs.error(w, r, http.StatusBadRequest, "factor must be a number and duration a duration such as 2h")
+			return
+		}
+
+		grace, err := service.StartGracePeriod(factor, duration)
+		if err != nil {
+			rs.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("cache grace period factor=%g until=%s set by client_ip=%s request_id=%s",
+			grace.Factor, grace.Until.Format(time.RFC3339), ClientIPFromContext(r.Context()), RequestIDFromContext(r.Context()))
+		rs.json(w, r, http.StatusOK, grace)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheGrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		auth       string
+		query      string
+		wantStatus int
+		wantFactor float64
+	}{
+		{name: "disabled", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=3&duration=2h", wantStatus: http.StatusNotFound},
+		{name: "started", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=3&duration=2h", wantStatus: http.StatusOK, wantFactor: 3},
+		{name: "wrong token", token: "s3cret", method: http.MethodPost, auth: "Bearer guess", query: "?factor=3&duration=2h", wantStatus: http.StatusUnauthorized},
+		{name: "missing token", token: "s3cret", method: http.MethodPost, query: "?factor=3&duration=2h", wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", token: "s3cret", method: http.MethodGet, auth: "Bearer s3cret", query: "?factor=3&duration=2h", wantStatus: http.StatusMethodNotAllowed},
+		{name: "missing params", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", wantStatus: http.StatusBadRequest},
+		{name: "factor not a number", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=lots&duration=2h", wantStatus: http.StatusBadRequest},
+		{name: "duration without a unit", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=3&duration=2", wantStatus: http.StatusBadRequest},
+		{name: "infinite factor", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=Inf&duration=2h", wantStatus: http.StatusBadRequest},
+		{name: "NaN factor", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=NaN&duration=2h", wantStatus: http.StatusBadRequest},
+		{name: "huge factor", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=1e300&duration=2h", wantStatus: http.StatusBadRequest},
+		{name: "factor below 1", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=0.5&duration=2h", wantStatus: http.StatusBadRequest},
+		{name: "negative duration", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", query: "?factor=3&duration=-2h", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, Options{CacheGraceToken: tt.token})
+			req := httptest.NewRequest(tt.method, "/cache/grace"+tt.query, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			grace := service.CacheStats().GracePeriod
+			if tt.wantStatus != http.StatusOK {
+				if grace != nil {
+					t.Errorf("grace period %v started by a rejected request", grace)
+				}
+				return
+			}
+			if grace == nil || grace.Factor != tt.wantFactor {
+				t.Fatalf("grace period = %v, want factor %g", grace, tt.wantFactor)
+			}
+			if got := decode(t, rec); got["factor"] != tt.wantFactor || got["until"] == nil {
+				t.Errorf("body = %v, want factor %g and an end time", got, tt.wantFactor)
+			}
+		})
+	}
+}
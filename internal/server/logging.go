@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// sampleResolution is the granularity of the sampling rate.
+const sampleResolution = 10000
+
+// statusRecorder captures the status code and size of a response for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Logging logs a sample of requests. A request is sampled when the hash of its request ID falls
+// within sampleRate, so any system that knows the ID can tell whether it was logged. Errors (every
+// 4xx and 5xx, including client disconnects recorded as 499) and requests slower than
+// slowThreshold are always logged. Requests that geocoded also log cache_hit, provider,
+// fallback_used and retries from a geocode.Trace. It must run after RequestID and ClientIP.
+func Logging(sampleRate float64, slowThreshold time.Duration) Middleware {
+	threshold := uint32(sampleRate * sampleResolution)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, trace := geocode.WithTrace(r.Context())
+			r = r.WithContext(ctx)
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			elapsed := time.Since(start)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			id := RequestIDFromContext(r.Context())
+			if rec.status < http.StatusBadRequest && elapsed < slowThreshold && !sampled(id, threshold) {
+				return
+			}
+			line := fmt.Sprintf("request_id=%s client_ip=%s method=%s path=%s status=%d bytes=%d duration=%s",
+				id, ClientIPFromContext(r.Context()), r.Method, r.URL.Path, rec.status, rec.bytes, elapsed)
+			if v := trace.Values(); v.Traced {
+				line += fmt.Sprintf(" cache_hit=%t provider=%s fallback_used=%t retries=%d",
+					v.CacheHit, v.Provider, v.FallbackUsed, v.Retries)
+			}
+			log.Print(line)
+		})
+	}
+}
+
+// sampled deterministically maps a request ID onto [0, sampleResolution) and compares it against
+// the threshold.
+func sampled(id string, threshold uint32) bool {
+	if threshold >= sampleResolution {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return h.Sum32()%sampleResolution < threshold
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// captureLog redirects the standard logger to a buffer for the duration of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	flags, output := log.Flags(), log.Writer()
+	log.SetFlags(0)
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetFlags(flags)
+		log.SetOutput(output)
+	})
+	return &buf
+}
+
+func TestSampledRate(t *testing.T) {
+	const requests = 20000
+	for _, rate := range []float64{0, 0.01, 0.1, 0.5, 1} {
+		t.Run(fmt.Sprint(rate), func(t *testing.T) {
+			threshold := uint32(rate * sampleResolution)
+			logged := 0
+			for i := 0; i < requests; i++ {
+				id := fmt.Sprintf("req-%d", i)
+				decision := sampled(id, threshold)
+				if sampled(id, threshold) != decision {
+					t.Fatalf("sampling %s is not deterministic", id)
+				}
+				if decision {
+					logged++
+				}
+			}
+			if got := float64(logged) / requests; math.Abs(got-rate) > 0.01 {
+				t.Errorf("sampled %.4f of requests, want %.4f", got, rate)
+			}
+		})
+	}
+}
+
+func TestLoggingAlwaysLogsErrorsAndSlowRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		delay      time.Duration
+		wantLogged bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "redirect", status: http.StatusFound},
+		{name: "bad request", status: http.StatusBadRequest, wantLogged: true},
+		{name: "not found", status: http.StatusNotFound, wantLogged: true},
+		{name: "server error", status: http.StatusBadGateway, wantLogged: true},
+		{name: "client closed", status: statusClientClosedRequest, wantLogged: true},
+		{name: "slow", status: http.StatusOK, delay: 20 * time.Millisecond, wantLogged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t)
+			handler := Chain(RequestID, Logging(0, 10*time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				w.WriteHeader(tt.status)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/geocode?address=S%C3%A9", nil)
+			req.Header.Set(requestIDHeader, "req-1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			line := buf.String()
+			if logged := line != ""; logged != tt.wantLogged {
+				t.Fatalf("logged = %v, want %v: %q", logged, tt.wantLogged, line)
+			}
+			if tt.wantLogged && !strings.Contains(line, fmt.Sprintf("request_id=req-1 client_ip= method=GET path=/geocode status=%d", tt.status)) {
+				t.Errorf("log line = %q", line)
+			}
+		})
+	}
+}
+
+func TestLoggingIncludesLookupDecisions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []geocode.Option
+		target string
+		want   string
+	}{
+		{name: "google answers", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9",
+			want: " cache_hit=false provider=google fallback_used=false retries=0"},
+		{name: "fallback to google", opts: []geocode.Option{geocode.WithProviders(geocode.NewStaticProvider(nil))}, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9",
+			want: " cache_hit=false provider=google fallback_used=true retries=0"},
+		{name: "no lookup", target: "/healthz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t)
+			service := newTestService(t, respond(http.StatusOK, sePayload), tt.opts...)
+			serve(t, service, Options{Middleware: []Middleware{RequestID, Logging(1, time.Second)}}, http.MethodGet, tt.target)
+
+			line := strings.TrimSpace(buf.String())
+			if tt.want == "" {
+				if strings.Contains(line, "cache_hit=") {
+					t.Errorf("log line = %q, want no lookup decisions", line)
+				}
+				return
+			}
+			if !strings.HasSuffix(line, tt.want) {
+				t.Errorf("log line = %q, want it to end with %q", line, tt.want)
+			}
+		})
+	}
+}
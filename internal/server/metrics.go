@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apigo/internal/metrics"
+)
+
+// Metrics reports every request to sink as a "requests" count and a "request.duration" timing,
+// tagged with the route it was registered under and the status. Responses with a 4xx or 5xx status
+// also count as "errors", except the 499 recorded when the client disconnects, which is not the
+// service's failure.
+func Metrics(sink metrics.Sink) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			route := routeOf(r)
+			tags := []string{"route:" + route, "status:" + strconv.Itoa(rec.status)}
+			sink.Count("requests", 1, tags...)
+			sink.Timing("request.duration", time.Since(start), tags...)
+			if rec.status >= http.StatusBadRequest && rec.status != statusClientClosedRequest {
+				sink.Count("errors", 1, tags...)
+			}
+		})
+	}
+}
+
+type routeKey struct{}
+
+// routeInfo describes the route a request was dispatched to.
+type routeInfo struct {
+	pattern    string
+	hideSource bool
+}
+
+// withRoute records the pattern a handler was registered under in the request context, for
+// metrics, along with whether the route hides the source of its results.
+func withRoute(pattern string, hideSource bool, next http.Handler) http.Handler {
+	route := routeInfo{pattern: pattern, hideSource: hideSource}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+	})
+}
+
+// routeOf returns the route a request is reported under: the pattern it was registered under, or
+// its path when it was not registered through RegisterRoutes. Paths that only reach the catch-all
+// "/" pattern are folded into "unmatched" to keep tag cardinality bounded.
+func routeOf(r *http.Request) string {
+	route, ok := r.Context().Value(routeKey{}).(routeInfo)
+	switch {
+	case !ok:
+		return r.URL.Path
+	case route.pattern == "/" && r.URL.Path != "/":
+		return "unmatched"
+	default:
+		return route.pattern
+	}
+}
+
+// sourceHidden reports whether the route a request was dispatched to hides the source of its
+// results.
+func sourceHidden(r *http.Request) bool {
+	route, _ := r.Context().Value(routeKey{}).(routeInfo)
+	return route.hideSource
+}
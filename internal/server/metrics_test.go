@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records every event as "name|tag,tag".
+type recordingSink struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *recordingSink) Count(name string, _ int64, tags ...string) {
+	s.record(name, tags)
+}
+
+func (s *recordingSink) Timing(name string, _ time.Duration, tags ...string) {
+	s.record(name, tags)
+}
+
+func (s *recordingSink) record(name string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, name+"|"+strings.Join(tags, ","))
+}
+
+func (s *recordingSink) Events() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+func TestMetricsRouteTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantRoute  string
+		wantStatus int
+	}{
+		{name: "geocode", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", wantRoute: "/geocode", wantStatus: http.StatusOK},
+		{name: "bad request", target: "/geocode", wantRoute: "/geocode", wantStatus: http.StatusBadRequest},
+		{name: "health", target: "/healthz", wantRoute: "/healthz", wantStatus: http.StatusOK},
+		{name: "demo page", target: "/", wantRoute: "/", wantStatus: http.StatusOK},
+		{name: "unknown path", target: "/wp-admin/setup.php?id=1", wantRoute: "unmatched", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, newTestService(t, respond(http.StatusOK, sePayload)), Options{DemoPage: true, Middleware: []Middleware{Metrics(sink)}})
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			tags := fmt.Sprintf("route:%s,status:%d", tt.wantRoute, tt.wantStatus)
+			want := []string{"requests|" + tags, "request.duration|" + tags}
+			if tt.wantStatus >= http.StatusBadRequest {
+				want = append(want, "errors|"+tags)
+			}
+			if got := sink.Events(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("events = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGeocodeDurationCacheLabel(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("address") == "atlantis" {
+			respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`)(w, r)
+			return
+		}
+		respond(http.StatusOK, sePayload)(w, r)
+	})
+	sink := &recordingSink{}
+	opts := Options{Metrics: sink}
+
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{name: "first lookup", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", want: "geocode.duration|cache:miss,outcome:ok"},
+		{name: "cache hit", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", want: "geocode.duration|cache:hit,outcome:ok"},
+		{name: "failed lookup", target: "/geocode?address=Atlantis", want: "geocode.duration|cache:miss,outcome:error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sink.Events())
+			serve(t, service, opts, http.MethodGet, tt.target)
+			events := sink.Events()[before:]
+			if len(events) != 1 || events[0] != tt.want {
+				t.Errorf("events = %q, want [%q]", events, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"apigo/internal/geocode"
+)
+
+// recordingMiddleware appends name to calls before and after the next handler runs.
+func recordingMiddleware(calls *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+" in")
+			next.ServeHTTP(w, r)
+			*calls = append(*calls, name+" out")
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	handler := Chain(
+		recordingMiddleware(&calls, "a"),
+		recordingMiddleware(&calls, "b"),
+		recordingMiddleware(&calls, "c"),
+	)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "handler")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"a in", "b in", "c in", "handler", "c out", "b out", "a out"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRegisterRoutesAppliesMiddleware(t *testing.T) {
+	var calls []string
+	service, err := geocode.New(geocode.WithAPIKey("test-key"))
+	if err != nil {
+		t.Fatalf("geocode.New: %v", err)
+	}
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, service, Options{Middleware: []Middleware{
+		recordingMiddleware(&calls, "first"),
+		recordingMiddleware(&calls, "second"),
+	}})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	want := []string{"first in", "second in", "second out", "first out"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+func TestInflightLimitShedsExcessRequests(t *testing.T) {
+	const limit, requests = 2, 20
+	var blocking atomic.Bool
+	var active, peak atomic.Int32
+	arrived := make(chan struct{}, requests)
+	release := make(chan struct{})
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if blocking.Load() {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			arrived <- struct{}{}
+			<-release
+		}
+		respond(http.StatusOK, sePayload)(w, r)
+	}, geocode.WithMaxInflightLookups(limit))
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, service, Options{})
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	if rec := get("/geocode?address=Pra%C3%A7a+da+S%C3%A9"); rec.Code != http.StatusOK {
+		t.Fatalf("warm-up status = %d: %s", rec.Code, rec.Body)
+	}
+	blocking.Store(true)
+
+	type response struct {
+		code       int
+		retryAfter string
+	}
+	lookup := func(i int, results chan<- response) {
+		rec := get(fmt.Sprintf("/geocode?address=Rua+%d", i))
+		results <- response{rec.Code, rec.Header().Get("Retry-After")}
+	}
+
+	admitted := make(chan response, limit)
+	for i := 0; i < limit; i++ {
+		go lookup(i, admitted)
+	}
+	for i := 0; i < limit; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the first lookups never reached the upstream")
+		}
+	}
+
+	// With every slot taken, further lookups fail fast instead of piling up behind the upstream.
+	shed := make(chan response, requests-limit)
+	for i := limit; i < requests; i++ {
+		go lookup(i, shed)
+	}
+	for i := limit; i < requests; i++ {
+		select {
+		case r := <-shed:
+			if r.code != http.StatusServiceUnavailable || r.retryAfter != overloadRetryAfter {
+				t.Errorf("excess request: status %d with Retry-After %q, want 503 with %q", r.code, r.retryAfter, overloadRetryAfter)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("an excess request waited for a slot instead of failing fast")
+		}
+	}
+	// Cache hits do not need a slot.
+	if rec := get("/geocode?address=Pra%C3%A7a+da+S%C3%A9"); rec.Code != http.StatusOK {
+		t.Errorf("cache hit status = %d while overloaded, want 200", rec.Code)
+	}
+
+	close(release)
+	for i := 0; i < limit; i++ {
+		if r := <-admitted; r.code != http.StatusOK {
+			t.Errorf("admitted request status = %d, want 200", r.code)
+		}
+	}
+	if got := peak.Load(); got > limit {
+		t.Errorf("peak concurrent upstream calls = %d, want at most %d", got, limit)
+	}
+}
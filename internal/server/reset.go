@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"apigo/internal/geocode"
+)
+
+// metricsResetHandler zeroes the in-process cache counters reported by /cache/stats. Callers must
+// send the configured token as "Authorization: Bearer <token>". Each reset is logged with the
+// caller's IP and request ID so that a sudden drop on a dashboard can be traced back.
+func metricsResetHandler(service *geocode.Service, token string, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if !rs.authorize(w, r, token) {
+			return
+		}
+
+		service.ResetCacheStats()
+		log.Printf("metrics reset by client_ip=%s request_id=%s", ClientIPFromContext(r.Context()), RequestIDFromContext(r.Context()))
+		rs.json(w, r, http.StatusOK, service.CacheStats())
+	}
+}
+
+// authorize checks that the request carries token as "Authorization: Bearer <token>", answering 401
+// when it does not.
+func (rs responder) authorize(w http.ResponseWriter, r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		rs.error(w, r, http.StatusUnauthorized, "invalid or missing token")
+		return false
+	}
+	return true
+}
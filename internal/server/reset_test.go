@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsReset(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		auth       string
+		wantStatus int
+		wantReset  bool
+	}{
+		{name: "disabled", method: http.MethodPost, auth: "Bearer s3cret", wantStatus: http.StatusNotFound},
+		{name: "reset", token: "s3cret", method: http.MethodPost, auth: "Bearer s3cret", wantStatus: http.StatusOK, wantReset: true},
+		{name: "wrong token", token: "s3cret", method: http.MethodPost, auth: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "missing token", token: "s3cret", method: http.MethodPost, wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", token: "s3cret", method: http.MethodGet, auth: "Bearer s3cret", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			opts := Options{MetricsResetToken: tt.token}
+			serve(t, service, opts, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+			serve(t, service, opts, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, opts)
+			req := httptest.NewRequest(tt.method, "/metrics/reset", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			stats := service.CacheStats()
+			if reset := stats.Hits == 0 && stats.Misses == 0; reset != tt.wantReset {
+				t.Errorf("hits %d and misses %d after the request, want reset %v", stats.Hits, stats.Misses, tt.wantReset)
+			}
+			if tt.wantReset {
+				if got := decode(t, rec); got["hits"] != float64(0) || got["misses"] != float64(0) {
+					t.Errorf("body = %v, want zero counters", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// responder writes JSON responses, either bare or wrapped in the standard envelope. Errors may
+// instead be written as RFC 7807 problem details.
+type responder struct {
+	envelope    bool
+	hideSource  bool
+	problemJSON bool
+}
+
+func newResponder(opts Options) responder {
+	return responder{envelope: opts.Envelope, hideSource: opts.HideSource, problemJSON: opts.ProblemJSON}
+}
+
+// envelope is the wrapper used for every response when Options.Envelope is enabled.
+type envelope struct {
+	Data  any            `json:"data"`
+	Error *envelopeError `json:"error,omitempty"`
+	Meta  envelopeMeta   `json:"meta"`
+}
+
+type envelopeError struct {
+	Message string `json:"message"`
+}
+
+// problem is an RFC 7807 problem details body. Errors are categorized by their status, so type is
+// about:blank and title is the standard status text.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+type envelopeMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Cached    *bool  `json:"cached,omitempty"`
+}
+
+// json writes payload with status.
+func (rs responder) json(w http.ResponseWriter, r *http.Request, status int, payload any) {
+	if !rs.envelope {
+		writeJSON(w, status, payload)
+		return
+	}
+	writeJSON(w, status, envelope{Data: payload, Meta: rs.meta(r, "")})
+}
+
+// result writes a successful lookup. In envelope mode the source of the result and whether it was
+// served from the cache are reported in the metadata, unless sources are hidden.
+func (rs responder) result(w http.ResponseWriter, r *http.Request, payload any, source string) {
+	if !rs.envelope {
+		writeJSON(w, http.StatusOK, payload)
+		return
+	}
+	writeJSON(w, http.StatusOK, envelope{Data: payload, Meta: rs.meta(r, source)})
+}
+
+// error writes an error message with status. Problem details take precedence over the envelope.
+func (rs responder) error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if rs.problemJSON {
+		writeProblem(w, r, status, message)
+		return
+	}
+	if !rs.envelope {
+		writeJSON(w, status, map[string]string{"error": message})
+		return
+	}
+	writeJSON(w, status, envelope{Error: &envelopeError{Message: message}, Meta: rs.meta(r, "")})
+}
+
+func (rs responder) meta(r *http.Request, source string) envelopeMeta {
+	meta := envelopeMeta{RequestID: RequestIDFromContext(r.Context())}
+	if source != "" && !rs.hideSource {
+		cached := source == "cache"
+		meta.Source = source
+		meta.Cached = &cached
+	}
+	return meta
+}
+
+// writeProblem writes an application/problem+json body. The instance identifies the request by its
+// request ID when one was assigned, and by its path otherwise.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	instance := r.URL.Path
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		instance = "urn:request-id:" + id
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResponderEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		target     string
+		wantStatus int
+		want       map[string]any
+	}{
+		{
+			name:       "bare result",
+			target:     "/geocode?address=Pra%C3%A7a+da+S%C3%A9",
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"address": "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil", "latitude": -23.5505191, "longitude": -46.6333094,
+				"source": "google", "country_code": "BR",
+				"components": map[string]any{"administrative_area_level_1": "SP", "country": "Brazil"},
+			},
+		},
+		{
+			name:       "bare error",
+			target:     "/geocode",
+			wantStatus: http.StatusBadRequest,
+			want:       map[string]any{"error": "address or postal_code query parameter is required"},
+		},
+		{
+			name:       "enveloped result",
+			opts:       Options{Envelope: true},
+			target:     "/geocode?address=Pra%C3%A7a+da+S%C3%A9",
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"data": map[string]any{
+					"address": "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil", "latitude": -23.5505191, "longitude": -46.6333094,
+					"source": "google", "country_code": "BR",
+					"components": map[string]any{"administrative_area_level_1": "SP", "country": "Brazil"},
+				},
+				"meta": map[string]any{"request_id": "req-1", "source": "google", "cached": false},
+			},
+		},
+		{
+			name:       "enveloped result with hidden source",
+			opts:       Options{Envelope: true, HideSource: true},
+			target:     "/healthz",
+			wantStatus: http.StatusOK,
+			want: map[string]any{
+				"data": map[string]any{"status": "ok"},
+				"meta": map[string]any{"request_id": "req-1"},
+			},
+		},
+		{
+			name:       "enveloped error",
+			opts:       Options{Envelope: true},
+			target:     "/geocode",
+			wantStatus: http.StatusBadRequest,
+			want: map[string]any{
+				"data":  nil,
+				"error": map[string]any{"message": "address or postal_code query parameter is required"},
+				"meta":  map[string]any{"request_id": "req-1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			tt.opts.Middleware = []Middleware{RequestID}
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, tt.opts)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set(requestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := decode(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponderProblemJSON(t *testing.T) {
+	zeroResults := respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`)
+	tests := []struct {
+		name       string
+		google     http.HandlerFunc
+		method     string
+		target     string
+		requestID  string
+		wantStatus int
+		wantDetail string
+		wantInst   string
+	}{
+		{name: "bad request", google: zeroResults, method: http.MethodGet, target: "/geocode", requestID: "req-1",
+			wantStatus: http.StatusBadRequest, wantDetail: "address or postal_code query parameter is required", wantInst: "urn:request-id:req-1"},
+		{name: "not found", google: zeroResults, method: http.MethodGet, target: "/geocode?address=Atlantis", requestID: "req-2",
+			wantStatus: http.StatusNotFound, wantDetail: "all providers failed: google: no results found", wantInst: "urn:request-id:req-2"},
+		{name: "method not allowed", google: zeroResults, method: http.MethodGet, target: "/bounds", requestID: "req-3",
+			wantStatus: http.StatusMethodNotAllowed, wantDetail: "method not allowed", wantInst: "urn:request-id:req-3"},
+		{name: "upstream rate limited", google: respond(http.StatusTooManyRequests, ""), method: http.MethodGet, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", requestID: "req-4",
+			wantStatus: http.StatusTooManyRequests, wantDetail: "all providers failed: google: google maps api returned status 429", wantInst: "urn:request-id:req-4"},
+		{name: "upstream failure", google: respond(http.StatusInternalServerError, ""), method: http.MethodGet, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", requestID: "req-5",
+			wantStatus: http.StatusBadGateway, wantDetail: "all providers failed: google: google maps api returned status 500", wantInst: "urn:request-id:req-5"},
+		{name: "without a request ID", google: zeroResults, method: http.MethodGet, target: "/geocode",
+			wantStatus: http.StatusBadRequest, wantDetail: "address or postal_code query parameter is required", wantInst: "/geocode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			opts := Options{ProblemJSON: true, Envelope: true}
+			if tt.requestID != "" {
+				opts.Middleware = []Middleware{RequestID}
+			}
+			RegisterRoutes(mux, newTestService(t, tt.google), opts)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(requestIDHeader, tt.requestID)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", got)
+			}
+			want := map[string]any{
+				"type":     "about:blank",
+				"title":    http.StatusText(tt.wantStatus),
+				"status":   float64(tt.wantStatus),
+				"detail":   tt.wantDetail,
+				"instance": tt.wantInst,
+			}
+			if got := decode(t, rec); !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %v\nwant %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// Supported values for the format query parameter.
+const (
+	formatDefault = ""
+	formatGeoJSON = "geojson"
+	formatWKT     = "wkt"
+)
+
+// resultResponse is the public representation of a geocode.Result. It is kept separate from the
+// internal type so that fields used for logging and metrics can be withheld from clients.
+type resultResponse struct {
+	Address   string         `json:"address"`
+	Latitude  float64        `json:"latitude"`
+	Longitude float64        `json:"longitude"`
+	Source    string         `json:"source,omitempty"`
+	Extra     map[string]any `json:"extra,omitempty"`
+
+	CountryCode string              `json:"country_code,omitempty"`
+	PlusCode    string              `json:"plus_code,omitempty"`
+	Components  *geocode.Components `json:"components,omitempty"`
+	Warnings    []string            `json:"warnings,omitempty"`
+
+	RetrievedAt *time.Time `json:"retrieved_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	Debug   *geocode.DebugInfo `json:"_debug,omitempty"`
+	Explain *explanation       `json:"_explain,omitempty"`
+}
+
+// validationResponse reports whether an address can be geocoded without revealing its coordinates.
+type validationResponse struct {
+	Valid        bool   `json:"valid"`
+	PartialMatch bool   `json:"partial_match"`
+	Precision    string `json:"precision,omitempty"`
+}
+
+// pointGeometry is a GeoJSON Point geometry. Coordinates are ordered as [longitude, latitude].
+type pointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geoJSONResponse struct {
+	Address  string        `json:"address"`
+	Geometry pointGeometry `json:"geometry"`
+	Source   string        `json:"source,omitempty"`
+
+	RetrievedAt *time.Time `json:"retrieved_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	Debug   *geocode.DebugInfo `json:"_debug,omitempty"`
+	Explain *explanation       `json:"_explain,omitempty"`
+}
+
+type wktResponse struct {
+	Address string `json:"address"`
+	WKT     string `json:"wkt"`
+	Source  string `json:"source,omitempty"`
+
+	RetrievedAt *time.Time `json:"retrieved_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	Debug   *geocode.DebugInfo `json:"_debug,omitempty"`
+	Explain *explanation       `json:"_explain,omitempty"`
+}
+
+// shapeResult converts a geocoding result into the representation requested by the client. The
+// result is a copy, so rounding coordinates or hiding the source never affects the cached value.
+// Debug information is only kept when debug is true. explain, when not nil, is attached as _explain.
+func shapeResult(result geocode.Result, format string, opts Options, debug bool, explain *explanation) (any, error) {
+	if !debug {
+		result.Debug = nil
+	}
+	if opts.CoordinateDecimals > 0 {
+		result.Latitude = roundTo(result.Latitude, opts.CoordinateDecimals)
+		result.Longitude = roundTo(result.Longitude, opts.CoordinateDecimals)
+	}
+	if opts.HideSource {
+		result.Source = ""
+	}
+	var retrievedAt, expiresAt *time.Time
+	if opts.Freshness && !result.RetrievedAt.IsZero() {
+		retrievedAt, expiresAt = &result.RetrievedAt, &result.ExpiresAt
+	}
+
+	switch format {
+	case formatDefault:
+		return resultResponse{
+			Address:   result.Address,
+			Latitude:  result.Latitude,
+			Longitude: result.Longitude,
+			Source:    result.Source,
+			Extra:     result.Extra,
+			Debug:     result.Debug,
+			Explain:   explain,
+
+			CountryCode: result.CountryCode,
+			PlusCode:    result.PlusCode,
+			Components:  result.Components,
+			Warnings:    result.Warnings,
+
+			RetrievedAt: retrievedAt,
+			ExpiresAt:   expiresAt,
+		}, nil
+	case formatGeoJSON:
+		return geoJSONResponse{
+			Address: result.Address,
+			Geometry: pointGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{result.Longitude, result.Latitude},
+			},
+			Source:  result.Source,
+			Debug:   result.Debug,
+			Explain: explain,
+
+			RetrievedAt: retrievedAt,
+			ExpiresAt:   expiresAt,
+		}, nil
+	case formatWKT:
+		return wktResponse{
+			Address: result.Address,
+			WKT:     formatWKTPoint(result.Latitude, result.Longitude),
+			Source:  result.Source,
+			Debug:   result.Debug,
+			Explain: explain,
+
+			RetrievedAt: retrievedAt,
+			ExpiresAt:   expiresAt,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// formatWKTPoint renders a coordinate as a WKT point, which uses longitude-first ordering.
+func formatWKTPoint(lat, lng float64) string {
+	return "POINT(" + strconv.FormatFloat(lng, 'f', -1, 64) + " " + strconv.FormatFloat(lat, 'f', -1, 64) + ")"
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
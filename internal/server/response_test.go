@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"apigo/internal/geocode"
+)
+
+func TestShapeResultFormats(t *testing.T) {
+	result := geocode.Result{
+		Address:   "Praça da Sé, São Paulo",
+		Latitude:  -23.5505191,
+		Longitude: -46.6333094,
+		Source:    "google",
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "default",
+			format: formatDefault,
+			want:   `{"address":"Praça da Sé, São Paulo","latitude":-23.5505191,"longitude":-46.6333094,"source":"google"}`,
+		},
+		{
+			name:   "geojson",
+			format: formatGeoJSON,
+			want:   `{"address":"Praça da Sé, São Paulo","geometry":{"type":"Point","coordinates":[-46.6333094,-23.5505191]},"source":"google"}`,
+		},
+		{
+			name:   "wkt",
+			format: formatWKT,
+			want:   `{"address":"Praça da Sé, São Paulo","wkt":"POINT(-46.6333094 -23.5505191)","source":"google"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := shapeResult(result, tt.format, Options{}, false, nil)
+			if err != nil {
+				t.Fatalf("shapeResult: %v", err)
+			}
+			got, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShapeResultUnknownFormat(t *testing.T) {
+	if _, err := shapeResult(geocode.Result{}, "kml", Options{}, false, nil); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
+
+func TestShapeResultRoundsCoordinates(t *testing.T) {
+	result := geocode.Result{Latitude: -23.5505191, Longitude: -46.6333094}
+
+	tests := []struct {
+		decimals int
+		lat, lng float64
+	}{
+		{decimals: 0, lat: -23.5505191, lng: -46.6333094},
+		{decimals: 1, lat: -23.6, lng: -46.6},
+		{decimals: 4, lat: -23.5505, lng: -46.6333},
+		{decimals: 6, lat: -23.550519, lng: -46.633309},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.decimals), func(t *testing.T) {
+			payload, err := shapeResult(result, formatDefault, Options{CoordinateDecimals: tt.decimals}, false, nil)
+			if err != nil {
+				t.Fatalf("shapeResult: %v", err)
+			}
+			got := payload.(resultResponse)
+			if got.Latitude != tt.lat || got.Longitude != tt.lng {
+				t.Errorf("coordinates = %v, %v; want %v, %v", got.Latitude, got.Longitude, tt.lat, tt.lng)
+			}
+		})
+	}
+
+	// Rounding applies to the alternate formats too.
+	payload, err := shapeResult(result, formatWKT, Options{CoordinateDecimals: 2}, false, nil)
+	if err != nil {
+		t.Fatalf("shapeResult: %v", err)
+	}
+	if got := payload.(wktResponse).WKT; got != "POINT(-46.63 -23.55)" {
+		t.Errorf("wkt = %s, want POINT(-46.63 -23.55)", got)
+	}
+}
+
+func TestShapeResultHideSource(t *testing.T) {
+	result := geocode.Result{Address: "Praça da Sé", Latitude: -23.55, Longitude: -46.63, Source: "cache"}
+
+	for _, format := range []string{formatDefault, formatGeoJSON, formatWKT} {
+		t.Run("format="+format, func(t *testing.T) {
+			for _, hide := range []bool{false, true} {
+				payload, err := shapeResult(result, format, Options{HideSource: hide}, false, nil)
+				if err != nil {
+					t.Fatalf("shapeResult: %v", err)
+				}
+				body, err := json.Marshal(payload)
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				var fields map[string]any
+				if err := json.Unmarshal(body, &fields); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				if _, shown := fields["source"]; shown == hide {
+					t.Errorf("hide=%v: source shown = %v in %s", hide, shown, body)
+				}
+			}
+		})
+	}
+	if result.Source != "cache" {
+		t.Fatalf("shaping changed the internal result's source to %q", result.Source)
+	}
+}
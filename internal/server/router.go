@@ -5,60 +5,428 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"apigo/internal/geocode"
+	"apigo/internal/metrics"
 )
 
+// Options customizes how responses are produced.
+type Options struct {
+	// CoordinateDecimals rounds output coordinates to this many decimal places. Zero disables rounding.
+	CoordinateDecimals int
+
+	// HideSource omits whether a result came from the cache or a provider from responses.
+	HideSource bool
+
+	// HideSourceRoutes omits the source like HideSource, but only from the routes registered under
+	// these patterns, so internal routes can keep reporting it.
+	HideSourceRoutes []string
+
+	// StrictQueryParams rejects /geocode requests with query parameters outside geocodeParams.
+	StrictQueryParams bool
+
+	// Debug allows clients to request the raw upstream response with debug=true.
+	Debug bool
+
+	// Explain allows clients to request a description of how the result was produced with
+	// explain=true.
+	Explain bool
+
+	// ProblemJSON writes errors as RFC 7807 application/problem+json instead of {"error": ...}.
+	ProblemJSON bool
+
+	// ValidateStyle selects how /validate reports its verdict: ValidateStyleBody (the default) or
+	// ValidateStyleStatus.
+	ValidateStyle string
+
+	// Freshness adds retrieved_at and expires_at to geocode responses.
+	Freshness bool
+
+	// Envelope wraps every response as {"data": ..., "error": ..., "meta": {...}}.
+	Envelope bool
+
+	// ReadinessTimeout bounds all dependency checks performed by /readyz.
+	ReadinessTimeout time.Duration
+
+	// DemoPage serves a minimal HTML page at / that calls /geocode, for manual testing.
+	DemoPage bool
+
+	// MetricsResetToken enables POST /metrics/reset, authenticated with this bearer token. The
+	// endpoint is not registered when it is empty.
+	MetricsResetToken string
+
+	// CacheGraceToken enables POST /cache/grace, authenticated with this bearer token. The endpoint
+	// is not registered when it is empty.
+	CacheGraceToken string
+
+	// RouteTimeouts sets the deadline of each route, by pattern. Nil uses DefaultRouteTimeouts.
+	RouteTimeouts map[string]time.Duration
+
+	// Metrics receives the "geocode.duration" timing of every /geocode lookup. Nil disables it.
+	Metrics metrics.Sink
+
+	// Middleware wraps every registered handler, in the order given. See Chain.
+	Middleware []Middleware
+}
+
 // RegisterRoutes configures the HTTP handlers for the service.
-func RegisterRoutes(mux *http.ServeMux, service *geocode.Service) {
-	mux.HandleFunc("/geocode", geocodeHandler(service))
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+func RegisterRoutes(mux *http.ServeMux, service *geocode.Service, opts Options) {
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.Nop{}
+	}
+	chain := Chain(opts.Middleware...)
+	timeouts := opts.RouteTimeouts
+	if timeouts == nil {
+		timeouts = DefaultRouteTimeouts
+	}
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, withRoute(pattern, opts.forRoute(pattern).HideSource, chain(Timeout(timeouts[pattern])(handler))))
+	}
+	rs := newResponder(opts)
+
+	handle("/geocode", geocodeHandler(service, opts.forRoute("/geocode")))
+	handle("/timezone", timezoneHandler(service, opts.forRoute("/timezone")))
+	handle("/bounds", boundsHandler(service, opts.forRoute("/bounds")))
+	handle("/normalize", normalizeHandler(service, opts.forRoute("/normalize")))
+	handle("/validate", validateHandler(service, opts.forRoute("/validate")))
+	handle("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		rs.json(w, r, http.StatusOK, map[string]string{"status": "ok"})
 	})
+	handle("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		checks, ready := service.CheckReadiness(r.Context(), opts.ReadinessTimeout)
+		if !ready {
+			rs.json(w, r, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "checks": checks})
+			return
+		}
+		rs.json(w, r, http.StatusOK, map[string]any{"status": "ready", "checks": checks})
+	})
+	handle("/cache/stats", func(w http.ResponseWriter, r *http.Request) {
+		rs.json(w, r, http.StatusOK, service.CacheStats())
+	})
+	handle("/quota", func(w http.ResponseWriter, r *http.Request) {
+		rs.json(w, r, http.StatusOK, service.QuotaStatus())
+	})
+	handle("/providers", func(w http.ResponseWriter, r *http.Request) {
+		rs.json(w, r, http.StatusOK, map[string]any{"providers": service.ProviderStatuses()})
+	})
+	if opts.MetricsResetToken != "" {
+		handle("/metrics/reset", metricsResetHandler(service, opts.MetricsResetToken, opts))
+	}
+	if opts.CacheGraceToken != "" {
+		handle("/cache/grace", cacheGraceHandler(service, opts.CacheGraceToken, opts))
+	}
+	if opts.DemoPage {
+		handle("/", demoHandler())
+	}
 }
 
-func geocodeHandler(service *geocode.Service) http.HandlerFunc {
+// forRoute returns opts as they apply to the handler registered under pattern.
+func (opts Options) forRoute(pattern string) Options {
+	if slices.Contains(opts.HideSourceRoutes, pattern) {
+		opts.HideSource = true
+	}
+	return opts
+}
+
+// geocodeParams are the query parameters understood by /geocode.
+var geocodeParams = map[string]bool{
+	"address":     true,
+	"postal_code": true,
+	"country":     true,
+	"format":      true,
+	"debug":       true,
+	"explain":     true,
+}
+
+// unknownParams returns the sorted names in query that are not in known.
+func unknownParams(query url.Values, known map[string]bool) []string {
+	var unknown []string
+	for name := range query {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// maxStructuredBodyBytes bounds the structured address accepted by POST /geocode.
+const maxStructuredBodyBytes = 16 << 10
+
+func geocodeHandler(service *geocode.Service, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		query := r.URL.Query()
+		if opts.StrictQueryParams {
+			if unknown := unknownParams(query, geocodeParams); len(unknown) > 0 {
+				rs.error(w, r, http.StatusBadRequest, "unknown query parameters: "+strings.Join(unknown, ", "))
+				return
+			}
+		}
+		// A POST carries a structured address in its body instead of the address query parameters.
+		var structured *geocode.StructuredAddress
+		if r.Method == http.MethodPost {
+			structured = &geocode.StructuredAddress{}
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStructuredBodyBytes)).Decode(structured); err != nil {
+				rs.error(w, r, http.StatusBadRequest, "request body must be a JSON object with street, city, state, postal_code or country")
+				return
+			}
+		}
+		address := strings.TrimSpace(query.Get("address"))
+		postalCode := strings.TrimSpace(query.Get("postal_code"))
+		country := strings.TrimSpace(query.Get("country"))
+		if structured == nil && address == "" && postalCode == "" {
+			rs.error(w, r, http.StatusBadRequest, "address or postal_code query parameter is required")
+			return
+		}
+
+		format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+		if format != formatDefault && format != formatGeoJSON && format != formatWKT {
+			rs.error(w, r, http.StatusBadRequest, "format must be one of geojson or wkt")
+			return
+		}
+
+		ctx := r.Context()
+		var trace *geocode.Trace
+		explain := opts.Explain && query.Get("explain") == "true"
+		if explain {
+			// Reuse the trace started by the logging middleware, if any, so the log line keeps its fields.
+			if trace = geocode.TraceFromContext(ctx); trace == nil {
+				ctx, trace = geocode.WithTrace(ctx)
+			}
+		}
+
+		var result geocode.Result
+		var err error
+		start := time.Now()
+		switch {
+		case structured != nil:
+			result, err = service.GeocodeStructured(ctx, *structured)
+		case postalCode != "":
+			result, err = service.GeocodePostalCode(ctx, postalCode, country)
+		default:
+			result, err = service.Geocode(ctx, address)
+		}
+		opts.Metrics.Timing("geocode.duration", time.Since(start), lookupTags(result, err)...)
+		if err != nil {
+			rs.lookupError(w, r, err)
+			return
+		}
+
+		var explained *explanation
+		if explain {
+			var normalization *geocode.Normalization
+			if structured == nil && postalCode == "" {
+				n := service.Normalize(address)
+				normalization = &n
+			}
+			explained = newExplanation(service, trace, normalization)
+		}
+
+		debug := opts.Debug && query.Get("debug") == "true"
+		payload, err := shapeResult(result, format, opts, debug, explained)
+		if err != nil {
+			rs.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		rs.result(w, r, payload, result.Source)
+	}
+}
+
+// lookupTags labels a lookup's duration with whether it was served from the cache and whether it
+// succeeded, so cache hits do not hide the latency of upstream calls.
+func lookupTags(result geocode.Result, err error) []string {
+	cache := "miss"
+	if err == nil && result.Source == "cache" {
+		cache = "hit"
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	return []string{"cache:" + cache, "outcome:" + outcome}
+}
+
+// Response styles for /validate.
+const (
+	// ValidateStyleBody answers 200 with a validationResponse for valid and invalid addresses alike.
+	ValidateStyleBody = "body"
+	// ValidateStyleStatus answers 204 without a body for a valid address and 422 with the usual
+	// error body for an address without results.
+	ValidateStyleStatus = "status"
+)
+
+func validateHandler(service *geocode.Service, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.Header().Set("Allow", http.MethodGet)
-			respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
 		address := strings.TrimSpace(r.URL.Query().Get("address"))
 		if address == "" {
-			respondError(w, http.StatusBadRequest, "address query parameter is required")
+			rs.error(w, r, http.StatusBadRequest, "address query parameter is required")
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-		defer cancel()
-
-		result, err := service.Geocode(ctx, address)
+		result, err := service.Geocode(r.Context(), address)
+		if errors.Is(err, geocode.ErrNoResults) {
+			if opts.ValidateStyle == ValidateStyleStatus {
+				rs.error(w, r, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			rs.json(w, r, http.StatusOK, validationResponse{Valid: false})
+			return
+		}
 		if err != nil {
-			switch {
-			case errors.Is(err, geocode.ErrNoResults):
-				respondError(w, http.StatusNotFound, err.Error())
-			case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
-				respondError(w, http.StatusGatewayTimeout, "geocoding request timed out")
-			default:
-				respondError(w, http.StatusBadGateway, err.Error())
+			rs.lookupError(w, r, err)
+			return
+		}
+
+		if opts.ValidateStyle == ValidateStyleStatus {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rs.result(w, r, validationResponse{
+			Valid:        true,
+			PartialMatch: result.PartialMatch,
+			Precision:    result.Precision,
+		}, result.Source)
+	}
+}
+
+func normalizeHandler(service *geocode.Service, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		query := r.URL.Query()
+		if !query.Has("address") {
+			rs.error(w, r, http.StatusBadRequest, "address query parameter is required")
+			return
+		}
+		rs.json(w, r, http.StatusOK, service.Normalize(query.Get("address")))
+	}
+}
+
+func timezoneHandler(service *geocode.Service, opts Options) http.HandlerFunc {
+	rs := newResponder(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			rs.error(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		query := r.URL.Query()
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(query.Get("lat")), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(query.Get("lng")), 64)
+		if latErr != nil || lngErr != nil {
+			rs.error(w, r, http.StatusBadRequest, "lat and lng query parameters must be numbers")
+			return
+		}
+
+		at := time.Now()
+		if raw := strings.TrimSpace(query.Get("timestamp")); raw != "" {
+			seconds, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				rs.error(w, r, http.StatusBadRequest, "timestamp must be a unix time in seconds")
+				return
 			}
+			at = time.Unix(seconds, 0)
+		}
+
+		tz, err := service.TimeZone(r.Context(), lat, lng, at)
+		if err != nil {
+			rs.lookupError(w, r, err)
 			return
 		}
 
-		respondJSON(w, http.StatusOK, result)
+		source := tz.Source
+		if opts.HideSource {
+			tz.Source = ""
+		}
+		rs.result(w, r, tz, source)
 	}
 }
 
-func respondJSON(w http.ResponseWriter, status int, payload any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(payload)
+// statusClientClosedRequest is the non-standard status, popularized by nginx, recorded when the
+// client goes away before the response is ready. The client never sees it; it only keeps
+// cancellations out of the 504s reported in logs and metrics.
+const statusClientClosedRequest = 499
+
+// clientCanceled reports whether the request was canceled by the client rather than by a deadline.
+// The route timeout wraps the request context with a deadline, so the context ends with
+// context.Canceled only when the connection's own context was canceled, which happens when the
+// client disconnects.
+func clientCanceled(r *http.Request) bool {
+	return errors.Is(r.Context().Err(), context.Canceled)
 }
 
-func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+// overloadRetryAfter is the Retry-After value, in seconds, sent when the service sheds load.
+const overloadRetryAfter = "1"
+
+// lookupError maps errors returned by the service to HTTP responses.
+func (rs responder) lookupError(w http.ResponseWriter, r *http.Request, err error) {
+	var upstream *geocode.UpstreamStatusError
+	switch {
+	case (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && clientCanceled(r):
+		rs.error(w, r, statusClientClosedRequest, "client closed request")
+	case errors.Is(err, geocode.ErrCountryRequired), errors.Is(err, geocode.ErrInvalidComponent),
+		errors.Is(err, geocode.ErrAddressRequired):
+		rs.error(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, geocode.ErrInvalidCoordinates):
+		rs.error(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, geocode.ErrNoResults):
+		rs.error(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, geocode.ErrCoordinatesInput), errors.Is(err, geocode.ErrAddressTooShort):
+		rs.error(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, geocode.ErrAddressBlocked):
+		rs.error(w, r, http.StatusForbidden, err.Error())
+	case errors.Is(err, geocode.ErrQuotaCapReached):
+		rs.error(w, r, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, geocode.ErrOverloaded):
+		w.Header().Set("Retry-After", overloadRetryAfter)
+		rs.error(w, r, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, geocode.ErrMissingAPIKey):
+		rs.error(w, r, http.StatusInternalServerError, err.Error())
+	case errors.Is(err, geocode.ErrLookupTimeout), errors.Is(err, geocode.ErrProviderTimeout):
+		rs.error(w, r, http.StatusGatewayTimeout, err.Error())
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		rs.error(w, r, http.StatusGatewayTimeout, "geocoding request timed out")
+	case errors.As(err, &upstream):
+		rs.error(w, r, upstreamStatus(upstream.StatusCode), err.Error())
+	default:
+		rs.error(w, r, http.StatusBadGateway, err.Error())
+	}
+}
+
+// upstreamStatus maps an HTTP status returned by Google to the status reported to the client:
+// rate limiting and unavailability are passed through, any other failure is a bad gateway.
+func upstreamStatus(status int) int {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return status
+	default:
+		return http.StatusBadGateway
+	}
 }
@@ -0,0 +1,438 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"apigo/internal/geocode"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestService creates a geocode.Service whose requests to the Google Maps APIs are answered by
+// google in process.
+func newTestService(t *testing.T, google http.HandlerFunc, opts ...geocode.Option) *geocode.Service {
+	t.Helper()
+	transport := geocode.WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			rec := httptest.NewRecorder()
+			google(rec, req)
+			return rec.Result(), nil
+		})
+	})
+	service, err := geocode.New(append([]geocode.Option{geocode.WithAPIKey("test-key"), transport}, opts...)...)
+	if err != nil {
+		t.Fatalf("geocode.New: %v", err)
+	}
+	return service
+}
+
+// respond answers every request with status and body.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+// serve sends a request for target to a mux with the routes of service registered with opts.
+func serve(t *testing.T, service *geocode.Service, opts Options, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, service, opts)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+// decode unmarshals the JSON body of rec into a map.
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+// losAngelesTimeZone is the Time Zone API response documented by Google for Los Angeles in winter.
+const losAngelesTimeZone = `{"dstOffset": 0, "rawOffset": -28800, "status": "OK", "timeZoneId": "America/Los_Angeles", "timeZoneName": "Pacific Standard Time"}`
+
+func TestTimezoneHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		opts       Options
+		wantStatus int
+		wantSource any
+	}{
+		{name: "ok", target: "/timezone?lat=34.0522&lng=-118.2437&timestamp=1705314600", wantStatus: http.StatusOK, wantSource: "google"},
+		{name: "hidden source", target: "/timezone?lat=34.0522&lng=-118.2437", opts: Options{HideSource: true}, wantStatus: http.StatusOK},
+		{name: "not a number", target: "/timezone?lat=north&lng=-118.2437", wantStatus: http.StatusBadRequest},
+		{name: "NaN", target: "/timezone?lat=NaN&lng=-118.2437", wantStatus: http.StatusBadRequest},
+		{name: "out of range", target: "/timezone?lat=95&lng=-118.2437", wantStatus: http.StatusBadRequest},
+		{name: "bad timestamp", target: "/timezone?lat=34.0522&lng=-118.2437&timestamp=today", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, losAngelesTimeZone))
+			rec := serve(t, service, tt.opts, http.MethodGet, tt.target)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			body := decode(t, rec)
+			if body["time_zone_id"] != "America/Los_Angeles" || body["raw_offset"] != float64(-28800) {
+				t.Errorf("body = %v", body)
+			}
+			if body["source"] != tt.wantSource {
+				t.Errorf("source = %v, want %v", body["source"], tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestHideSourceRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		target     string
+		wantSource bool
+	}{
+		{name: "designated route", opts: Options{HideSourceRoutes: []string{"/geocode"}}, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9"},
+		{name: "other route", opts: Options{HideSourceRoutes: []string{"/geocode"}}, target: "/validate?address=Pra%C3%A7a+da+S%C3%A9", wantSource: true},
+		{name: "hidden everywhere", opts: Options{HideSource: true}, target: "/validate?address=Pra%C3%A7a+da+S%C3%A9"},
+		{name: "not hidden", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9", wantSource: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			tt.opts.Envelope = true
+			rec := serve(t, service, tt.opts, http.MethodGet, tt.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+
+			body := decode(t, rec)
+			meta, _ := body["meta"].(map[string]any)
+			data, _ := body["data"].(map[string]any)
+			_, inMeta := meta["source"]
+			_, inData := data["source"]
+			if inMeta != tt.wantSource || (inData && !tt.wantSource) {
+				t.Errorf("source in meta = %v, in data = %v; want reported %v: %v", inMeta, inData, tt.wantSource, body)
+			}
+		})
+	}
+}
+
+// sePayload is a trimmed Geocoding API response for Praça da Sé in São Paulo.
+const sePayload = `{"status": "OK", "results": [{
+  "formatted_address": "Praça da Sé - Sé, São Paulo - SP, 01001-000, Brazil",
+  "address_components": [
+    {"long_name": "São Paulo", "short_name": "SP", "types": ["administrative_area_level_1", "political"]},
+    {"long_name": "Brazil", "short_name": "BR", "types": ["country", "political"]}
+  ],
+  "geometry": {"location": {"lat": -23.5505191, "lng": -46.6333094}, "location_type": "GEOMETRIC_CENTER"}
+}]}`
+
+func TestGeocodeDebugField(t *testing.T) {
+	tests := []struct {
+		name      string
+		capture   int
+		opts      Options
+		query     string
+		wantDebug bool
+	}{
+		{name: "capture disabled", opts: Options{Debug: true}, query: "&debug=true"},
+		{name: "mode disabled", capture: 1024, query: "&debug=true"},
+		{name: "not requested", capture: 1024, opts: Options{Debug: true}},
+		{name: "enabled and requested", capture: 1024, opts: Options{Debug: true}, query: "&debug=true", wantDebug: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload), geocode.WithDebugCapture(tt.capture))
+
+			// The second request is a cache hit, which must keep the captured response.
+			for _, want := range []string{"google", "cache"} {
+				rec := serve(t, service, tt.opts, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9"+tt.query)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+				body := decode(t, rec)
+				if body["source"] != want {
+					t.Fatalf("source = %v, want %s", body["source"], want)
+				}
+				debug, ok := body["_debug"].(map[string]any)
+				if ok != tt.wantDebug {
+					t.Fatalf("%s: _debug present = %v, want %v", want, ok, tt.wantDebug)
+				}
+				if ok && debug["upstream"] != sePayload {
+					t.Errorf("%s: _debug.upstream = %v, want the raw Google response", want, debug["upstream"])
+				}
+			}
+		})
+	}
+}
+
+func TestProvidersHandler(t *testing.T) {
+	service := newTestService(t, respond(http.StatusOK, `{"status": "REQUEST_DENIED", "error_message": "The provided API key is invalid."}`))
+	serve(t, service, Options{}, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+
+	rec := serve(t, service, Options{}, http.MethodGet, "/providers")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	providers, _ := decode(t, rec)["providers"].([]any)
+	if len(providers) != 1 {
+		t.Fatalf("providers = %v, want the google provider only", providers)
+	}
+	google, _ := providers[0].(map[string]any)
+	want := map[string]any{
+		"name":                 "google",
+		"default":              true,
+		"healthy":              false,
+		"consecutive_failures": float64(1),
+		"last_error":           "google maps api error: The provided API key is invalid.",
+	}
+	for field, value := range want {
+		if google[field] != value {
+			t.Errorf("%s = %v, want %v", field, google[field], value)
+		}
+	}
+	if _, ok := google["last_call"]; !ok {
+		t.Error("last_call is missing")
+	}
+}
+
+func TestGeocodeZeroResultsIsNotFound(t *testing.T) {
+	service := newTestService(t, respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`))
+	rec := serve(t, service, Options{}, http.MethodGet, "/geocode?address=Rua+Inexistente%2C+999")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404: %s", rec.Code, rec.Body)
+	}
+	if message, _ := decode(t, rec)["error"].(string); !strings.Contains(message, geocode.ErrNoResults.Error()) {
+		t.Errorf("error = %q, want it to report %q", message, geocode.ErrNoResults)
+	}
+}
+
+func TestNormalizeHandlerNeverCallsGoogle(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to Google: %s", r.URL)
+	})
+	rec := serve(t, service, Options{}, http.MethodGet, "/normalize?address=++Pra%C3%A7a+da+S%C3%A9+")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	body := decode(t, rec)
+	if body["raw"] != "  Praça da Sé " || body["preprocessed"] != "Praça da Sé" || body["key"] != "praça da sé" {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestGeocodeFreshnessIsOptIn(t *testing.T) {
+	tests := []struct {
+		name      string
+		freshness bool
+	}{
+		{name: "disabled"},
+		{name: "enabled", freshness: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			rec := serve(t, service, Options{Freshness: tt.freshness}, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			body := decode(t, rec)
+			_, hasRetrieved := body["retrieved_at"]
+			_, hasExpires := body["expires_at"]
+			if hasRetrieved != tt.freshness || hasExpires != tt.freshness {
+				t.Errorf("retrieved_at present %v, expires_at present %v, want %v", hasRetrieved, hasExpires, tt.freshness)
+			}
+		})
+	}
+}
+
+func TestGeocodeMapsUpstreamStatus(t *testing.T) {
+	tests := []struct {
+		upstream int
+		want     int
+	}{
+		{upstream: http.StatusTooManyRequests, want: http.StatusTooManyRequests},
+		{upstream: http.StatusServiceUnavailable, want: http.StatusServiceUnavailable},
+		{upstream: http.StatusInternalServerError, want: http.StatusBadGateway},
+		{upstream: http.StatusBadGateway, want: http.StatusBadGateway},
+		{upstream: http.StatusGatewayTimeout, want: http.StatusBadGateway},
+		{upstream: http.StatusForbidden, want: http.StatusBadGateway},
+		{upstream: http.StatusBadRequest, want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.upstream), func(t *testing.T) {
+			service := newTestService(t, respond(tt.upstream, `{"error": "upstream failure"}`))
+			rec := serve(t, service, Options{}, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			want := fmt.Sprintf("returned status %d", tt.upstream)
+			if message, _ := decode(t, rec)["error"].(string); !strings.Contains(message, want) {
+				t.Errorf("error = %q, want it to mention %q", message, want)
+			}
+		})
+	}
+}
+
+func TestGeocodeQuotaCapIsServiceUnavailable(t *testing.T) {
+	service := newTestService(t, respond(http.StatusOK, sePayload), geocode.WithDailyCap(1, 0))
+	serve(t, service, Options{}, http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9")
+
+	rec := serve(t, service, Options{}, http.MethodGet, "/geocode?address=Avenida+Paulista")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", rec.Code, rec.Body)
+	}
+	rec = serve(t, service, Options{}, http.MethodGet, "/quota")
+	if body := decode(t, rec); body["used"] != float64(1) || body["remaining"] != float64(0) {
+		t.Errorf("quota = %v, want 1 used and none remaining", body)
+	}
+}
+
+func TestGeocodeSpecialCharactersReachGoogleIntact(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "Apt #3 & 4, Rua Augusta", want: "apt #3 & 4, rua augusta"},
+		{address: "Rua A+B, 10", want: "rua a+b, 10"},
+		{address: "Av. C&A = 5% off?", want: "av. c&a = 5% off?"},
+		{address: "Rua São João, nº 100/102", want: "rua são joão, nº 100/102"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			var received []string
+			service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				received = append(received, r.URL.Query().Get("address"))
+				respond(http.StatusOK, sePayload)(w, r)
+			})
+			target := "/geocode?address=" + url.QueryEscape(tt.address)
+
+			for _, wantSource := range []string{"google", "cache"} {
+				rec := serve(t, service, Options{}, http.MethodGet, target)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+				if source := decode(t, rec)["source"]; source != wantSource {
+					t.Errorf("source = %v, want %s", source, wantSource)
+				}
+			}
+			if len(received) != 1 || received[0] != tt.want {
+				t.Errorf("Google received %q, want exactly [%q]", received, tt.want)
+			}
+			if key := service.Normalize(tt.address).Key; key != tt.want {
+				t.Errorf("cache key = %q, want %q", key, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeocodeStrictQueryParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		target     string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "strict typo", strict: true, target: "/geocode?adress=Pra%C3%A7a+da+S%C3%A9", wantStatus: http.StatusBadRequest,
+			wantError: "unknown query parameters: adress"},
+		{name: "strict lists every unknown param", strict: true, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9&zoom=3&lang=pt", wantStatus: http.StatusBadRequest,
+			wantError: "unknown query parameters: lang, zoom"},
+		{name: "strict known params", strict: true, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9&country=BR&debug=false", wantStatus: http.StatusOK},
+		{name: "lenient typo is ignored", target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9&adress=x", wantStatus: http.StatusOK},
+		{name: "lenient typo alone still needs an address", target: "/geocode?adress=Pra%C3%A7a+da+S%C3%A9", wantStatus: http.StatusBadRequest,
+			wantError: "address or postal_code query parameter is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			rec := serve(t, service, Options{StrictQueryParams: tt.strict}, http.MethodGet, tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantError != "" {
+				if got := decode(t, rec)["error"]; got != tt.wantError {
+					t.Errorf("error = %q, want %q", got, tt.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestGeocodeAddressTooShortIsBadRequest(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Google was called for an address below the minimum length")
+	})
+	rec := serve(t, service, Options{}, http.MethodGet, "/geocode?address=S%C3%A9")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	if got := decode(t, rec)["error"]; got != geocode.ErrAddressTooShort.Error() {
+		t.Errorf("error = %q, want %q", got, geocode.ErrAddressTooShort)
+	}
+}
+
+func TestGeocodeStructuredBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantAddress   string
+		wantComponent string
+	}{
+		{name: "structured address", body: `{"street": "Praça da Sé", "city": "São Paulo", "postal_code": "01001-000", "country": "BR"}`,
+			wantStatus: http.StatusOK, wantAddress: "praça da sé, são paulo", wantComponent: "postal_code:01001-000|country:BR"},
+		{name: "malformed body", body: `["Praça da Sé"]`, wantStatus: http.StatusBadRequest},
+		{name: "no fields", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				respond(http.StatusOK, sePayload)(w, r)
+			})
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, Options{})
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/geocode", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if query.Get("address") != tt.wantAddress || query.Get("components") != tt.wantComponent {
+				t.Errorf("upstream address %q with components %q, want %q with %q",
+					query.Get("address"), query.Get("components"), tt.wantAddress, tt.wantComponent)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedRequest builds a request to target carrying body, signed with secret at timestamp.
+func signedRequest(secret []byte, method, target, body, nonce string, timestamp time.Time) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, ts+"\n"+nonce+"\n"+method+"\n"+req.URL.RequestURI()+"\n"+body)
+	req.Header.Set(timestampHeader, ts)
+	req.Header.Set(nonceHeader, nonce)
+	req.Header.Set(signatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("partner-secret")
+	now := time.Now()
+
+	var gotBody string
+	handler := VerifySignature(secret, time.Minute, Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "valid",
+			request: func() *http.Request {
+				return signedRequest(secret, http.MethodPost, "/geocode/batch", `["Praça da Sé"]`, "nonce-1", now)
+			},
+			wantStatus: http.StatusNoContent,
+			wantBody:   `["Praça da Sé"]`,
+		},
+		{
+			name: "replayed nonce",
+			request: func() *http.Request {
+				return signedRequest(secret, http.MethodPost, "/geocode/batch", `["Praça da Sé"]`, "nonce-1", now)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "replayed after the window",
+			request: func() *http.Request {
+				return signedRequest(secret, http.MethodPost, "/geocode/batch", `["Praça da Sé"]`, "nonce-2", now.Add(-2*time.Minute))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered body",
+			request: func() *http.Request {
+				req := signedRequest(secret, http.MethodPost, "/geocode/batch", `["Praça da Sé"]`, "nonce-3", now)
+				req.Body = io.NopCloser(strings.NewReader(`["Avenida Paulista"]`))
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered query",
+			request: func() *http.Request {
+				req := signedRequest(secret, http.MethodGet, "/geocode?address=S%C3%A9", "", "nonce-4", now)
+				req.URL.RawQuery = "address=Paulista"
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong secret",
+			request: func() *http.Request {
+				return signedRequest([]byte("other"), http.MethodGet, "/geocode?address=S%C3%A9", "", "nonce-5", now)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unsigned",
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/geocode?address=S%C3%A9", nil) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unsigned probe",
+			request:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/healthz", nil) },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "oversized body",
+			request: func() *http.Request {
+				return signedRequest(secret, http.MethodPost, "/geocode/batch", strings.Repeat("a", maxSignedBodyBytes+1), "nonce-6", now)
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	// The cases share the middleware so that replays see the nonces used before them.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.request())
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if gotBody != tt.wantBody {
+				t.Errorf("handler read body %q, want %q", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureErrorFormat(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            Options
+		wantContentType string
+		want            map[string]any
+	}{
+		{
+			name:            "default",
+			wantContentType: "application/json",
+			want:            map[string]any{"error": "missing or malformed request signature"},
+		},
+		{
+			name:            "problem details",
+			opts:            Options{ProblemJSON: true},
+			wantContentType: "application/problem+json",
+			want: map[string]any{
+				"type": "about:blank", "title": "Unauthorized", "status": float64(http.StatusUnauthorized),
+				"detail": "missing or malformed request signature", "instance": "/geocode",
+			},
+		},
+		{
+			name:            "envelope",
+			opts:            Options{Envelope: true},
+			wantContentType: "application/json",
+			want: map[string]any{
+				"data": nil, "error": map[string]any{"message": "missing or malformed request signature"}, "meta": map[string]any{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := VerifySignature([]byte("partner-secret"), time.Minute, tt.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("unsigned request reached the handler")
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geocode?address=S%C3%A9", nil))
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401: %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := decode(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultRouteTimeouts bounds the handling of each route that calls a provider. POST /bounds
+// geocodes many addresses and gets more time than single lookups. Routes missing from the map have
+// no deadline of their own.
+var DefaultRouteTimeouts = map[string]time.Duration{
+	"/geocode":  3 * time.Second,
+	"/validate": 3 * time.Second,
+	"/timezone": 3 * time.Second,
+	"/bounds":   4 * time.Second,
+}
+
+// RouteTimeouts returns DefaultRouteTimeouts with overrides applied. A zero override removes the
+// route's deadline.
+func RouteTimeouts(overrides map[string]time.Duration) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(DefaultRouteTimeouts)+len(overrides))
+	for route, d := range DefaultRouteTimeouts {
+		timeouts[route] = d
+	}
+	for route, d := range overrides {
+		timeouts[route] = d
+	}
+	return timeouts
+}
+
+// Timeout sets a deadline of d on the request context. Handlers report an expired deadline as 504
+// through the usual error response. A non-positive d leaves the request without a deadline.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
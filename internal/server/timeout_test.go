@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]time.Duration
+		route     string
+		want      time.Duration
+		wantOK    bool
+	}{
+		{name: "default", route: "/geocode", want: 3 * time.Second, wantOK: true},
+		{name: "bounds default", route: "/bounds", want: 4 * time.Second, wantOK: true},
+		{name: "override", overrides: map[string]time.Duration{"/bounds": 30 * time.Second}, route: "/bounds", want: 30 * time.Second, wantOK: true},
+		{name: "new route", overrides: map[string]time.Duration{"/normalize": time.Second}, route: "/normalize", want: time.Second, wantOK: true},
+		{name: "zero override", overrides: map[string]time.Duration{"/geocode": 0}, route: "/geocode", wantOK: true},
+		{name: "unlisted route", route: "/healthz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RouteTimeouts(tt.overrides)[tt.route]
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("RouteTimeouts()[%q] = %s, %v; want %s, %v", tt.route, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+	if DefaultRouteTimeouts["/bounds"] != 4*time.Second {
+		t.Error("RouteTimeouts modified DefaultRouteTimeouts")
+	}
+}
+
+func TestRegisterRoutesAppliesRouteTimeouts(t *testing.T) {
+	timeouts := map[string]time.Duration{
+		"/geocode":  time.Second,
+		"/validate": 2 * time.Second,
+		"/bounds":   5 * time.Second,
+	}
+	tests := []struct {
+		method string
+		target string
+		body   string
+		route  string
+	}{
+		{method: http.MethodGet, target: "/geocode?address=S%C3%A3o+Paulo", route: "/geocode"},
+		{method: http.MethodGet, target: "/validate?address=S%C3%A3o+Paulo", route: "/validate"},
+		{method: http.MethodPost, target: "/bounds", body: `{"addresses": ["São Paulo"]}`, route: "/bounds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			var (
+				mu        sync.Mutex
+				remaining time.Duration
+				deadline  bool
+			)
+			service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				var d time.Time
+				d, deadline = r.Context().Deadline()
+				remaining = time.Until(d)
+				mu.Unlock()
+				cities(w, r)
+			}, geocode.WithMaxLookupDuration(10*time.Second))
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, Options{RouteTimeouts: timeouts})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			want := timeouts[tt.route]
+			if !deadline || remaining > want || remaining < want-500*time.Millisecond {
+				t.Errorf("upstream deadline in %s (set %v), want about %s", remaining, deadline, want)
+			}
+		})
+	}
+}
+
+func TestRouteTimeoutExpiry(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, service, Options{RouteTimeouts: map[string]time.Duration{"/geocode": 20 * time.Millisecond}})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geocode?address=S%C3%A3o+Paulo", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504: %s", rec.Code, rec.Body)
+	}
+	got := decode(t, rec)
+	if _, ok := got["error"].(string); !ok || len(got) != 1 {
+		t.Errorf("body = %v, want the standard error shape", got)
+	}
+}
+
+func TestGeocodeDeadlineVersusClientCancel(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		clientQuits bool
+		wantStatus  int
+		wantErrors  bool
+	}{
+		{name: "server deadline", timeout: 20 * time.Millisecond, wantStatus: http.StatusGatewayTimeout, wantErrors: true},
+		{name: "client canceled", timeout: 10 * time.Second, clientQuits: true, wantStatus: statusClientClosedRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.clientQuits {
+					cancel()
+				}
+				<-r.Context().Done()
+			})
+			sink := &recordingSink{}
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, Options{
+				RouteTimeouts: map[string]time.Duration{"/geocode": tt.timeout},
+				Middleware:    []Middleware{Metrics(sink)},
+			})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geocode?address=S%C3%A3o+Paulo", nil).WithContext(ctx))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			counted := false
+			for _, event := range sink.Events() {
+				counted = counted || strings.HasPrefix(event, "errors|")
+			}
+			if counted != tt.wantErrors {
+				t.Errorf("counted as an error = %v, want %v: %q", counted, tt.wantErrors, sink.Events())
+			}
+		})
+	}
+}
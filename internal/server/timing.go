@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"apigo/internal/geocode"
+)
+
+// Response headers reporting where a request spent its time, in milliseconds.
+const (
+	upstreamDurationHeader = "X-Upstream-Duration"
+	totalDurationHeader    = "X-Total-Duration"
+)
+
+// timingWriter sets the duration headers just before the response header is written. The upstream
+// duration is left out when hideUpstream is set.
+type timingWriter struct {
+	http.ResponseWriter
+	start        time.Time
+	timer        *geocode.UpstreamTimer
+	hideUpstream bool
+	wroteHeader  bool
+}
+
+func (w *timingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		if !w.hideUpstream {
+			h.Set(upstreamDurationHeader, formatMillis(w.timer.Duration()))
+		}
+		h.Set(totalDurationHeader, formatMillis(time.Since(w.start)))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Timing reports the time spent calling providers and the total handling time in the
+// X-Upstream-Duration and X-Total-Duration headers. Cache hits report zero upstream time, which
+// would reveal the source of the result, so routes hiding the source only report the total.
+func Timing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, timer := geocode.WithUpstreamTimer(r.Context())
+		tw := &timingWriter{ResponseWriter: w, start: time.Now(), timer: timer, hideUpstream: sourceHidden(r)}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	})
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
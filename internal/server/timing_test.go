@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimingHeaders(t *testing.T) {
+	const upstreamDelay = 30 * time.Millisecond
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(upstreamDelay)
+		respond(http.StatusOK, sePayload)(w, r)
+	})
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, service, Options{Middleware: []Middleware{Timing}})
+
+	millis := func(t *testing.T, rec *httptest.ResponseRecorder, header string) float64 {
+		t.Helper()
+		ms, err := strconv.ParseFloat(rec.Header().Get(header), 64)
+		if err != nil {
+			t.Fatalf("%s = %q: %v", header, rec.Header().Get(header), err)
+		}
+		return ms
+	}
+
+	tests := []struct {
+		name         string
+		wantSource   string
+		wantUpstream bool
+	}{
+		{name: "miss", wantSource: "google", wantUpstream: true},
+		{name: "cache hit", wantSource: "cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geocode?address=Pra%C3%A7a+da+S%C3%A9", nil))
+			if rec.Code != http.StatusOK || decode(t, rec)["source"] != tt.wantSource {
+				t.Fatalf("status %d with body %s, want a %s result", rec.Code, rec.Body, tt.wantSource)
+			}
+
+			upstream, total := millis(t, rec, upstreamDurationHeader), millis(t, rec, totalDurationHeader)
+			if !tt.wantUpstream {
+				if got := rec.Header().Get(upstreamDurationHeader); got != "0.000" {
+					t.Errorf("%s = %q, want 0.000", upstreamDurationHeader, got)
+				}
+			} else if upstream < float64(upstreamDelay/time.Millisecond) {
+				t.Errorf("upstream = %.3fms, want at least the %s upstream delay", upstream, upstreamDelay)
+			}
+			if total < upstream {
+				t.Errorf("total = %.3fms, want at least the upstream %.3fms", total, upstream)
+			}
+		})
+	}
+}
+
+func TestTimingHeadersWithHiddenSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Options
+		target       string
+		wantUpstream bool
+	}{
+		{name: "hidden everywhere", opts: Options{HideSource: true}, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9"},
+		{name: "hidden on the route", opts: Options{HideSourceRoutes: []string{"/geocode"}}, target: "/geocode?address=Pra%C3%A7a+da+S%C3%A9"},
+		{name: "hidden on another route", opts: Options{HideSourceRoutes: []string{"/geocode"}}, target: "/validate?address=Pra%C3%A7a+da+S%C3%A9", wantUpstream: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, respond(http.StatusOK, sePayload))
+			tt.opts.Middleware = []Middleware{Timing}
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, service, tt.opts)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			if _, ok := rec.Header()[upstreamDurationHeader]; ok != tt.wantUpstream {
+				t.Errorf("%s set = %v, want %v", upstreamDurationHeader, ok, tt.wantUpstream)
+			}
+			if rec.Header().Get(totalDurationHeader) == "" {
+				t.Errorf("%s missing", totalDurationHeader)
+			}
+		})
+	}
+}
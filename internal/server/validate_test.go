@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// partialPayload is a Geocoding API response that matched only part of the address.
+const partialPayload = `{"status": "OK", "results": [{
+  "formatted_address": "São Paulo - SP, Brazil",
+  "geometry": {"location": {"lat": -23.5557714, "lng": -46.6395571}, "location_type": "APPROXIMATE"},
+  "partial_match": true
+}]}`
+
+func TestValidateHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		google     http.HandlerFunc
+		target     string
+		wantStatus int
+		want       map[string]any
+	}{
+		{
+			name:       "valid",
+			google:     respond(http.StatusOK, sePayload),
+			target:     "/validate?address=Pra%C3%A7a+da+S%C3%A9",
+			wantStatus: http.StatusOK,
+			want:       map[string]any{"valid": true, "partial_match": false, "precision": "GEOMETRIC_CENTER"},
+		},
+		{
+			name:       "no results",
+			google:     respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`),
+			target:     "/validate?address=Rua+Inexistente%2C+999",
+			wantStatus: http.StatusOK,
+			want:       map[string]any{"valid": false, "partial_match": false},
+		},
+		{
+			name:       "partial match",
+			google:     respond(http.StatusOK, partialPayload),
+			target:     "/validate?address=Rua+Inexistente%2C+S%C3%A3o+Paulo",
+			wantStatus: http.StatusOK,
+			want:       map[string]any{"valid": true, "partial_match": true, "precision": "APPROXIMATE"},
+		},
+		{
+			name:       "missing address",
+			google:     respond(http.StatusOK, sePayload),
+			target:     "/validate",
+			wantStatus: http.StatusBadRequest,
+			want:       map[string]any{"error": "address query parameter is required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, newTestService(t, tt.google), Options{}, http.MethodGet, tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			// Coordinates must never be exposed by the validation endpoint.
+			if got := decode(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHandlerStatusStyle(t *testing.T) {
+	tests := []struct {
+		name       string
+		google     http.HandlerFunc
+		target     string
+		wantStatus int
+		want       map[string]any
+	}{
+		{
+			name:       "valid",
+			google:     respond(http.StatusOK, sePayload),
+			target:     "/validate?address=Pra%C3%A7a+da+S%C3%A9",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "partial match is valid",
+			google:     respond(http.StatusOK, partialPayload),
+			target:     "/validate?address=Rua+Inexistente%2C+S%C3%A3o+Paulo",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "no results",
+			google:     respond(http.StatusOK, `{"status": "ZERO_RESULTS", "results": []}`),
+			target:     "/validate?address=Rua+Inexistente%2C+999",
+			wantStatus: http.StatusUnprocessableEntity,
+			want:       map[string]any{"error": "all providers failed: google: no results found"},
+		},
+		{
+			name:       "missing address",
+			google:     respond(http.StatusOK, sePayload),
+			target:     "/validate",
+			wantStatus: http.StatusBadRequest,
+			want:       map[string]any{"error": "address query parameter is required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, newTestService(t, tt.google), Options{ValidateStyle: ValidateStyleStatus}, http.MethodGet, tt.target)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.want == nil {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %q, want none", rec.Body)
+				}
+				return
+			}
+			if got := decode(t, rec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
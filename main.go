@@ -1,41 +1,328 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"apigo/internal/alert"
 	"apigo/internal/config"
 	"apigo/internal/geocode"
+	"apigo/internal/metrics"
 	"apigo/internal/server"
 )
 
+// cacheTTL is the lifetime of cached geocoding results.
+const cacheTTL = 30 * time.Minute
+
 func main() {
-	if err := config.LoadFromEnvFile(".env"); err != nil {
-		log.Fatalf("failed to load .env file: %v", err)
+	// The .env file is kept out of the process environment so that a reload sees lines removed
+	// from it as unset.
+	cfg, err := config.LoadWithEnvFile(".env")
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	cfg, err := config.Load()
+	filter, err := geocode.NewFilter(cfg.AddressBlocklist, cfg.AddressAllowlist)
 	if err != nil {
-		log.Fatalf("failed to load configuration: %v", err)
+		log.Fatalf("failed to build address filter: %v", err)
+	}
+
+	var sink metrics.Sink = metrics.Nop{}
+	if cfg.StatsDAddr != "" {
+		statsd, err := metrics.NewStatsD(cfg.StatsDAddr, cfg.StatsDPrefix, cfg.StatsDTags)
+		if err != nil {
+			log.Fatalf("failed to set up statsd: %v", err)
+		}
+		defer statsd.Close()
+		sink = statsd
+	}
+
+	opts := []geocode.Option{
+		geocode.WithAPIKey(cfg.GoogleAPIKey),
+		geocode.WithChannel(cfg.GoogleChannel),
+		geocode.WithCacheTTL(cacheTTL),
+		geocode.WithCacheShards(cfg.CacheShards),
+		geocode.WithCanonicalCache(cfg.CanonicalCacheSize),
+		geocode.WithProviderTTLs(cfg.ProviderTTLs),
+		geocode.WithPrecisionTTLs(cfg.PrecisionTTLs),
+		geocode.WithStatsWindow(cfg.CacheStatsWindow),
+		geocode.WithChurnLimit(cfg.CacheChurnLimit, cfg.CacheChurnWindow, func(paused bool, newKeys int) {
+			if paused {
+				log.Printf("cache churn: %d new keys within %s, not caching new entries until the window ends", newKeys, cfg.CacheChurnWindow)
+			} else {
+				log.Printf("cache churn: caching of new entries resumed after %d new keys in the last window", newKeys)
+			}
+		}),
+		geocode.WithFailureTTLs(cfg.FailureCacheTTL, cfg.NoResultsCacheTTL),
+		geocode.WithMaxLookupDuration(cfg.MaxLookupDuration),
+		geocode.WithTransportSettings(geocode.TransportSettings{
+			IdleConnTimeout:     cfg.OutboundIdleConnTimeout,
+			MaxIdleConnsPerHost: cfg.OutboundMaxIdleConnsPerHost,
+			KeepAlive:           cfg.OutboundKeepAlive,
+		}),
+		geocode.WithProviderTimeout(cfg.ProviderTimeout),
+		geocode.WithMaxProviderAttempts(cfg.MaxProviderAttempts),
+		geocode.WithFilter(filter),
+		geocode.WithCollisionGuard(cfg.CollisionGuard),
+		geocode.WithDefaultCountry(cfg.DefaultCountry...),
+		geocode.WithMetrics(sink),
+		geocode.WithMaxInflightLookups(cfg.MaxInflightLookups),
+		geocode.WithDailyCap(cfg.DailyUpstreamCap, cfg.DailyCapReset),
+		geocode.WithCoordinateInputMode(cfg.CoordinateInputMode),
+		geocode.WithMinAddressLength(cfg.MinAddressLength),
+		geocode.WithTieBreak(cfg.ResultTieBreak),
+	}
+	if cfg.AddressFormat == "components" {
+		opts = append(opts, geocode.WithComponentAddress(cfg.AddressComponentOrder...))
+	}
+	if cfg.GoogleClientID != "" {
+		opts = append(opts, geocode.WithPremiumCredentials(cfg.GoogleClientID, cfg.GoogleSigningSecret))
+	}
+	if cfg.DebugResponses {
+		opts = append(opts, geocode.WithDebugCapture(cfg.DebugMaxBytes))
+	}
+	if cfg.StaticDatasetPath != "" {
+		static, err := geocode.LoadStaticProvider(cfg.StaticDatasetPath)
+		if err != nil {
+			log.Fatalf("failed to load static dataset: %v", err)
+		}
+		opts = append(opts, geocode.WithProviders(static))
+	}
+	if cfg.ChaosFailureRate > 0 || cfg.ChaosLatency > 0 {
+		log.Printf("WARNING: CHAOS MODE ENABLED: failing %.0f%% of provider calls and delaying each by %s; never run this in production",
+			cfg.ChaosFailureRate*100, cfg.ChaosLatency)
+		opts = append(opts, geocode.WithProviderDecorator(func(p geocode.Provider) geocode.Provider {
+			return geocode.NewChaosProvider(p, cfg.ChaosFailureRate, cfg.ChaosLatency)
+		}))
+	}
+
+	if cfg.AdaptiveRateMax > 0 {
+		opts = append(opts, geocode.WithProviderDecorator(func(p geocode.Provider) geocode.Provider {
+			if p.Name() != "google" {
+				return p
+			}
+			return geocode.NewAdaptiveProvider(p, cfg.AdaptiveRateMin, cfg.AdaptiveRateMax)
+		}))
+	}
+
+	// fatal receives the first unrecoverable upstream error, which shuts the server down.
+	fatal := make(chan error, 1)
+	if cfg.FailFastDeniedThreshold > 0 {
+		opts = append(opts, geocode.WithFailFastOnDenied(cfg.FailFastDeniedThreshold, func(err error) {
+			select {
+			case fatal <- err:
+			default:
+			}
+		}))
+	}
+
+	if cfg.AlertWebhookURL != "" {
+		webhook := alert.NewWebhook(cfg.AlertWebhookURL, max(cfg.AlertThreshold, 1), cfg.AlertWindow, cfg.AlertCooldown)
+		opts = append(opts, geocode.WithErrorObserver(webhook.Observe))
+	}
+
+	service, err := geocode.New(opts...)
+	if err != nil {
+		log.Fatalf("failed to create geocoding service: %v", err)
+	}
+
+	if cfg.CacheSnapshotEnabled {
+		restored, err := service.LoadSnapshot(cfg.CacheSnapshotPath)
+		if err != nil {
+			log.Printf("failed to load cache snapshot: %v", err)
+		} else {
+			log.Printf("restored %d cache entries from %s", restored, cfg.CacheSnapshotPath)
+		}
+	}
+
+	clientIP, err := server.ClientIP(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	routeTimeouts := server.RouteTimeouts(cfg.RouteTimeouts)
+	serverOpts := server.Options{
+		CoordinateDecimals: cfg.CoordinateDecimals,
+		HideSource:         cfg.HideSource,
+		HideSourceRoutes:   cfg.HideSourceRoutes,
+		StrictQueryParams:  cfg.StrictQueryParams,
+		Debug:              cfg.DebugResponses,
+		Explain:            cfg.ExplainResponses,
+		Envelope:           cfg.ResponseEnvelope,
+		ProblemJSON:        cfg.ProblemJSON,
+		Freshness:          cfg.ResponseFreshness,
+		ValidateStyle:      cfg.ValidateResponseStyle,
+		ReadinessTimeout:   cfg.ReadinessTimeout,
+		DemoPage:           cfg.DemoPage,
+		MetricsResetToken:  cfg.MetricsResetToken,
+		CacheGraceToken:    cfg.CacheGraceToken,
+		RouteTimeouts:      routeTimeouts,
+		Metrics:            sink,
+	}
+
+	middleware := []server.Middleware{
+		server.RequestID,
+		server.Timing,
+		server.Metrics(sink),
+		clientIP,
+		server.Logging(cfg.LogSampleRate, cfg.LogSlowThreshold),
+	}
+	middlewareNames := []string{"request_id", "timing", "metrics", "client_ip", "logging"}
+	if cfg.InboundSigningSecret != "" {
+		middleware = append(middleware, server.VerifySignature([]byte(cfg.InboundSigningSecret), cfg.InboundSigningWindow, serverOpts))
+		middlewareNames = append(middlewareNames, "signature")
+	}
+	serverOpts.Middleware = middleware
+
+	diagnostics := cfg.Diagnostics()
+	diagnostics.CacheTTL = cacheTTL.String()
+	diagnostics.Middleware = middlewareNames
+	if summary, err := json.Marshal(diagnostics); err == nil {
+		log.Printf("startup config: %s", summary)
+	}
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux, service, serverOpts)
+
+	srv := newHTTPServer(cfg, mux, routeTimeouts)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go reloadOnHangup(ctx, service)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLSEnabled() {
+			log.Printf("starting TLS server on port %s", cfg.ServerPort)
+			serveErr <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+		log.Printf("starting server on port %s", cfg.ServerPort)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	pprofSrv := newPprofServer(cfg)
+	if pprofSrv != nil {
+		go func() {
+			log.Printf("starting pprof server on %s", cfg.PprofAddr)
+			if err := pprofSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("pprof server failed: %v", err)
+			}
+		}()
+	}
+
+	var fatalErr error
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	case <-ctx.Done():
+		log.Printf("shutting down server")
+		shutdown(srv, pprofSrv)
+	case fatalErr = <-fatal:
+		log.Printf("FATAL: %v; the API key is likely revoked or restricted, shutting down server", fatalErr)
+		shutdown(srv, pprofSrv)
+	}
+
+	if cfg.CacheSnapshotEnabled {
+		if err := service.SaveSnapshot(cfg.CacheSnapshotPath); err != nil {
+			log.Printf("failed to save cache snapshot: %v", err)
+		} else {
+			log.Printf("saved cache snapshot to %s", cfg.CacheSnapshotPath)
+		}
+	}
+
+	if fatalErr != nil {
+		os.Exit(1)
 	}
+}
 
-	service := geocode.NewService(cfg.GoogleAPIKey, 30*time.Minute)
+// shutdown gracefully stops the API server and, when running, the pprof server.
+func shutdown(srv, pprofSrv *http.Server) {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown failed: %v", err)
+	}
+	if pprofSrv != nil {
+		_ = pprofSrv.Shutdown(shutdownCtx)
+	}
+}
 
+// newPprofServer builds the admin listener serving net/http/pprof, or returns nil when profiling
+// is disabled.
+func newPprofServer(cfg config.Config) *http.Server {
+	if !cfg.EnablePprof {
+		return nil
+	}
 	mux := http.NewServeMux()
-	server.RegisterRoutes(mux, service)
+	server.RegisterPprof(mux)
+	// Profiles can take longer than the API write timeout, so the admin listener keeps the
+	// http.Server defaults.
+	return &http.Server{Addr: cfg.PprofAddr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
+
+// newHTTPServer builds the HTTP server. The write timeout leaves a second past the longest route
+// timeout so that a route hitting its deadline can still write its 504. When TLS is enabled, HTTP/2
+// is negotiated automatically by ListenAndServeTLS through ALPN.
+func newHTTPServer(cfg config.Config, handler http.Handler, routeTimeouts map[string]time.Duration) *http.Server {
+	writeTimeout := 5 * time.Second
+	for _, d := range routeTimeouts {
+		writeTimeout = max(writeTimeout, d+time.Second)
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
+	if cfg.TLSEnabled() {
+		srv.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			NextProtos: []string{"h2", "http/1.1"},
+		}
+	}
+	return srv
+}
+
+// reloadOnHangup re-reads the .env file and environment on SIGHUP and swaps in the new address
+// filter. The file is parsed afresh, so rules whose lines were deleted from it are dropped. An
+// invalid configuration is logged and the current filter is kept.
+func reloadOnHangup(ctx context.Context, service *geocode.Service) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
 
-	log.Printf("starting server on port %s", cfg.ServerPort)
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		log.Fatalf("server failed: %v", err)
+		cfg, err := config.LoadWithEnvFile(".env")
+		if err != nil {
+			log.Printf("reload: invalid configuration, keeping current filter: %v", err)
+			continue
+		}
+		filter, err := geocode.NewFilter(cfg.AddressBlocklist, cfg.AddressAllowlist)
+		if err != nil {
+			log.Printf("reload: invalid address filter, keeping current filter: %v", err)
+			continue
+		}
+		service.SetFilter(filter)
+		log.Printf("reload: address filter updated")
 	}
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"apigo/internal/config"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key to dir and returns their paths
+// along with the DER-encoded certificate.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, der []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "apigo test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile, der
+}
+
+func TestNewHTTPServerServesTLSWithHTTP2(t *testing.T) {
+	certFile, keyFile, der := writeSelfSignedCert(t, t.TempDir())
+	cfg := config.Config{ServerPort: "0", TLSCertFile: certFile, TLSKeyFile: keyFile}
+
+	srv := newHTTPServer(cfg, http.NotFoundHandler(), nil)
+	if srv.TLSConfig == nil {
+		t.Fatal("TLS config is not set while TLS is enabled")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+	t.Cleanup(func() { srv.Close() })
+
+	pool := x509.NewCertPool()
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	pool.AddCert(cert)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: pool, NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 || !bytes.Equal(state.PeerCertificates[0].Raw, der) {
+		t.Fatal("the server did not present the configured certificate")
+	}
+	if state.NegotiatedProtocol != "h2" {
+		t.Fatalf("negotiated protocol = %q, want h2", state.NegotiatedProtocol)
+	}
+}
+
+func TestNewHTTPServerPlainHTTP(t *testing.T) {
+	srv := newHTTPServer(config.Config{ServerPort: "8080"}, http.NotFoundHandler(), nil)
+	if srv.TLSConfig != nil {
+		t.Fatal("TLS config is set while TLS is disabled")
+	}
+	if srv.Addr != ":8080" {
+		t.Fatalf("addr = %q, want :8080", srv.Addr)
+	}
+}
+
+func TestNewPprofServer(t *testing.T) {
+	if srv := newPprofServer(config.Config{PprofAddr: "127.0.0.1:6060"}); srv != nil {
+		t.Fatalf("pprof server = %+v with ENABLE_PPROF off, want none", srv)
+	}
+
+	srv := newPprofServer(config.Config{EnablePprof: true, PprofAddr: "127.0.0.1:6060"})
+	if srv == nil {
+		t.Fatal("no pprof server with ENABLE_PPROF on")
+	}
+	if srv.Addr != "127.0.0.1:6060" {
+		t.Errorf("addr = %q, want 127.0.0.1:6060", srv.Addr)
+	}
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/pprof/cmdline"} {
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+	}
+}